@@ -22,7 +22,7 @@ import models
 
 
 XREF_RE = re.compile(r'k8s-gubernator.appspot.com/build(/[^])\s]+/\d+)')
-APPROVERS_RE = re.compile(r'<!-- META={"?approvers"?:\[([^]]*)\]} -->')
+APPROVERS_RE = re.compile(r'<!-- META={"?approvers"?:\[([^]]*)\][^\n]*} -->')
 
 
 class Deduper(object):
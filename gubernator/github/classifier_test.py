@@ -266,11 +266,19 @@ class CalculateTest(unittest.TestCase):
         expect('before\n<!-- META={approvers:[someone]} -->', ['someone'])
         expect('<!-- META={approvers:[someone,else]} -->', ['someone', 'else'])
         expect('<!-- META={approvers:[someone,else]} -->', ['someone', 'else'])
+        # The per-file approval status follows the approvers.
+        expect('<!-- META={"approvers":["Alice"],"files":{"a":[],"b":["Bill"]},"unapproved":["a"]} -->',
+               ['Alice'])
 
         # The META format is *supposed* to be JSON, but a recent change broke it.
         # Support both formats so it can be fixed in the future.
         expect('<!-- META={"approvers":["username"]} -->\n', ['username'])
 
+        # Version 2 adds fields after the approvers, and a SUMMARY comment.
+        expect('<!-- META={"approvers":["a","b"],"files":{"x":[]},"unapproved":["x"],"version":2} -->\n'
+               '<!-- SUMMARY={"approved":false,"approved_files":0,"total_files":1,"suggested":2} -->',
+               ['a', 'b'])
+
 
 class CommentsTest(unittest.TestCase):
     def test_basic(self):
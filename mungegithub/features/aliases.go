@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
 	"k8s.io/kubernetes/pkg/util/sets"
 	"k8s.io/test-infra/mungegithub/github"
@@ -61,6 +63,14 @@ type Aliases struct {
 
 var _ feature = &Aliases{}
 
+// NewAliases creates Aliases from an already loaded mapping between aliases
+// and lists of members.
+func NewAliases(aliasMap map[string][]string) *Aliases {
+	return &Aliases{
+		data: &aliasData{AliasMap: aliasMap},
+	}
+}
+
 func init() {
 	RegisterFeature(&Aliases{})
 }
@@ -112,18 +122,73 @@ func (a *Aliases) EachLoop() error {
 		if err := yaml.Unmarshal(fileContents, &data); err != nil {
 			return fmt.Errorf("Failed to decode the alias file: %v", err)
 		}
+		if cycle := FindAliasCycle(data.AliasMap); cycle != nil {
+			return fmt.Errorf("Circular alias definition in the alias file: %s", strings.Join(cycle, " -> "))
+		}
 		a.data = &data
 		a.prevHash = hash
 	}
 	return nil
 }
 
+// FindAliasCycle returns the aliases of a cycle of aliases listing each
+// other, starting and ending with the same alias, or nil if there is none.
+// The first cycle in alias order is returned.
+func FindAliasCycle(aliasMap map[string][]string) []string {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	path := []string{}
+	var visit func(alias string) []string
+	visit = func(alias string) []string {
+		switch state[alias] {
+		case visiting:
+			for i, a := range path {
+				if a == alias {
+					return append(append([]string{}, path[i:]...), alias)
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[alias] = visiting
+		path = append(path, alias)
+		for _, member := range aliasMap[alias] {
+			if _, ok := aliasMap[member]; !ok {
+				continue
+			}
+			if cycle := visit(member); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[alias] = visited
+		return nil
+	}
+
+	aliases := []string{}
+	for alias := range aliasMap {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if cycle := visit(alias); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
 // AddFlags will add any request flags to the cobra `cmd`
 func (a *Aliases) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&a.AliasFile, "alias-file", "", "File wherein team members and aliases exist.")
 }
 
-// Expand takes aliases and expands them into owner lists.
+// Expand takes aliases and expands them into owner lists. Aliases are
+// only expanded once, so that members that are aliases themselves, e.g.
+// in a circular definition, are listed as is.
 func (a *Aliases) Expand(toExpand sets.String) sets.String {
 	expanded := sets.String{}
 	for _, owner := range toExpand.List() {
@@ -132,6 +197,12 @@ func (a *Aliases) Expand(toExpand sets.String) sets.String {
 	return expanded
 }
 
+// IsAlias returns true if name is a known alias.
+func (a *Aliases) IsAlias(name string) bool {
+	_, ok := a.data.AliasMap[name]
+	return ok
+}
+
 func (a *Aliases) resolve(owner string) []string {
 	if val, ok := a.data.AliasMap[owner]; ok {
 		return val
@@ -93,3 +93,66 @@ func TestExpandAliases(t *testing.T) {
 		}
 	}
 }
+
+// aliasYamlReader reads its content as the alias file.
+type aliasYamlReader string
+
+func (a aliasYamlReader) read() ([]byte, error) {
+	return []byte(a), nil
+}
+
+func TestCircularAliases(t *testing.T) {
+	tests := []struct {
+		name          string
+		aliasYaml     string
+		expectedError string
+	}{
+		{
+			name:      "No cycle",
+			aliasYaml: "aliases:\n  team/a:\n    - team/b\n  team/b:\n    - u1\n",
+		},
+		{
+			name:          "Direct cycle",
+			aliasYaml:     "aliases:\n  team/a:\n    - team/b\n  team/b:\n    - u1\n    - team/a\n",
+			expectedError: "Circular alias definition in the alias file: team/a -> team/b -> team/a",
+		},
+		{
+			name:          "Indirect cycle",
+			aliasYaml:     "aliases:\n  team/a:\n    - u1\n    - team/b\n  team/b:\n    - team/c\n  team/c:\n    - team/a\n",
+			expectedError: "Circular alias definition in the alias file: team/a -> team/b -> team/c -> team/a",
+		},
+		{
+			name:          "Self cycle",
+			aliasYaml:     "aliases:\n  team/a:\n    - team/a\n",
+			expectedError: "Circular alias definition in the alias file: team/a -> team/a",
+		},
+	}
+
+	for _, test := range tests {
+		a := Aliases{
+			aliasReader: aliasYamlReader(test.aliasYaml),
+			IsEnabled:   true,
+		}
+		if err := a.Initialize(&github_util.Config{}); err != nil {
+			t.Fatalf("%v", err)
+		}
+		err := a.EachLoop()
+		if test.expectedError == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if test.expectedError != "" && (err == nil || err.Error() != test.expectedError) {
+			t.Errorf("%s: expected error %q, got: %v", test.name, test.expectedError, err)
+		}
+	}
+
+	// Expanding cyclic aliases loaded without the check terminates.
+	a := NewAliases(map[string][]string{
+		"team/a": {"u1", "team/b"},
+		"team/b": {"team/c"},
+		"team/c": {"team/a"},
+	})
+	expanded := a.Expand(sets.NewString("team/a", "team/c"))
+	if expected := sets.NewString("u1", "team/a", "team/b"); !expanded.Equal(expected) {
+		t.Errorf("expected: %v, got: %v", expected.List(), expanded.List())
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"k8s.io/kubernetes/pkg/util/sets"
 	"k8s.io/kubernetes/pkg/util/yaml"
@@ -41,12 +42,66 @@ const (
 	RepoFeatureName = "gitrepos"
 	// Github's api uses "" (empty) string as basedir by convention but it's clearer to use "/"
 	baseDirConvention = ""
+	// filterSeparator separates the directory from the filter pattern in
+	// the keys of filtered OWNERS. It can't appear in canonical paths.
+	filterSeparator = "//"
 )
 
+// FilteredOwnersKey returns the key used by FindApproverOwnersForPath for
+// the files of dir matching the given filter pattern of its OWNERS file.
+func FilteredOwnersKey(dir, filter string) string {
+	return dir + filterSeparator + filter
+}
+
+// SplitOwnersKey returns the directory and the filter pattern (empty for
+// the whole directory) of a key returned by FindApproverOwnersForPath.
+func SplitOwnersKey(key string) (dir, filter string) {
+	if i := strings.Index(key, filterSeparator); i >= 0 {
+		return key[:i], key[i+len(filterSeparator):]
+	}
+	return key, ""
+}
+
+// ownersFilter lists the approvers of the files matching a pattern.
+type ownersFilter struct {
+	Approvers []string `json:"approvers" yaml:"approvers"`
+}
+
+// approversFilter is a compiled filter pattern of an OWNERS file.
+type approversFilter struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+type dirOptions struct {
+	// NoParentOwners stops the inheritance of approvers and reviewers
+	// from the parent directories.
+	NoParentOwners bool `json:"no_parent_owners,omitempty" yaml:"no_parent_owners,omitempty"`
+	// RequiredApprovals is the number of approvers needed to approve
+	// the directory, 1 if unset.
+	RequiredApprovals int `json:"required_approvals,omitempty" yaml:"required_approvals,omitempty"`
+}
+
 type assignmentConfig struct {
 	Assignees []string `json:"assignees" yaml:"assignees"`
 	Approvers []string `json:"approvers" yaml:"approvers"`
 	Reviewers []string `json:"reviewers" yaml:"reviewers"`
+	// EmeritusApprovers can still approve but are never suggested.
+	EmeritusApprovers []string `json:"emeritus_approvers" yaml:"emeritus_approvers"`
+	// RequiredApprovers must all approve the PRs touching the directory.
+	RequiredApprovers []string `json:"required_approvers" yaml:"required_approvers"`
+	// BackupApprovers can approve but are only suggested when no other
+	// approver can.
+	BackupApprovers []string `json:"backup_approvers" yaml:"backup_approvers"`
+	// Labels should be applied to the PRs touching the directory.
+	Labels []string `json:"labels" yaml:"labels"`
+	// RequiredLabels must be applied to the PRs touching the directory,
+	// e.g. by their author, unlike Labels which are applied by a bot.
+	RequiredLabels []string   `json:"required_labels" yaml:"required_labels"`
+	Options        dirOptions `json:"options,omitempty" yaml:"options,omitempty"`
+	// Filters map regexps to the additional approvers of the matching
+	// files, relative to the directory.
+	Filters map[string]ownersFilter `json:"filters,omitempty" yaml:"filters,omitempty"`
 }
 
 // RepoInfo provides information about users in OWNERS files in a git repo
@@ -54,12 +109,48 @@ type RepoInfo struct {
 	BaseDir      string
 	EnableMdYaml bool
 	UseReviewers bool
+	// CaseInsensitivePaths matches the paths of the files with the OWNERS
+	// directories regardless of case, e.g. for repos checked out on
+	// case-insensitive filesystems.
+	CaseInsensitivePaths bool
+	// MaxOwnersDepth caps how many directories are walked up from a file
+	// looking for its OWNERS. Past it, the OWNERS of the repo root are
+	// used. Zero means no limit.
+	MaxOwnersDepth int
+	// OwnersFileNames are the names of the ownership files, by
+	// precedence: only the first one found in a directory is used.
+	// Defaults to OWNERS. Evaluations can use other names, see
+	// WithOwnersFileNames.
+	OwnersFileNames []string
 
 	enabled    bool
 	projectDir string
 	approvers  map[string]sets.String
 	reviewers  map[string]sets.String
-	config     *github.Config
+	emeritus   map[string]sets.String
+	backup     map[string]sets.String
+	required   map[string]sets.String
+	labels     map[string]sets.String
+	// requiredLabels are the RequiredLabels of the OWNERS files.
+	requiredLabels map[string]sets.String
+	filters        map[string][]approversFilter
+	options        map[string]dirOptions
+	config         *github.Config
+	// lowerDirs maps the lowercase OWNERS directories to their actual
+	// name, built with the other maps for CaseInsensitivePaths so that
+	// lookups only read them.
+	lowerDirs map[string]string
+	// ownersFiles maps the OWNERS directories to the name of their
+	// ownership file, see OwnersFileNames.
+	ownersFiles map[string]string
+	// parseErrors maps the OWNERS directories to the error parsing
+	// their ownership file. They are left out of the other maps.
+	parseErrors map[string]error
+	// views caches the RepoInfos reading the checkout with other
+	// ownership file names, see WithOwnersFileNames. They are dropped
+	// when the checkout is read again.
+	views     map[string]*RepoInfo
+	viewsLock sync.Mutex
 }
 
 func init() {
@@ -103,7 +194,7 @@ func (o *RepoInfo) walkFunc(path string, info os.FileInfo, err error) error {
 
 	// '.md' files may contain assignees at the top of the file in a yaml header
 	// Flag guarded because this is only enabled in some repos
-	if o.EnableMdYaml && filename != ownerFilename && strings.HasSuffix(filename, "md") {
+	if o.EnableMdYaml && !o.isOwnersFileName(filename) && strings.HasSuffix(filename, "md") {
 		// Parse the yaml header from the file if it exists and marshal into the config
 		if err := decodeAssignmentConfig(path, c); err != nil {
 			glog.Errorf("%v", err)
@@ -118,11 +209,24 @@ func (o *RepoInfo) walkFunc(path string, info os.FileInfo, err error) error {
 		}
 		o.approvers[path] = sets.NewString(c.Approvers...)
 		o.approvers[path].Insert(c.Assignees...)
+		o.approvers[path].Insert(c.EmeritusApprovers...)
+		o.approvers[path].Insert(c.RequiredApprovers...)
+		o.approvers[path].Insert(c.BackupApprovers...)
 		o.reviewers[path] = sets.NewString(c.Reviewers...)
+		o.emeritus[path] = sets.NewString(c.EmeritusApprovers...)
+		o.backup[path] = sets.NewString(c.BackupApprovers...)
+		o.required[path] = sets.NewString(c.RequiredApprovers...)
+		o.labels[path] = sets.NewString(c.Labels...)
+		o.requiredLabels[path] = sets.NewString(c.RequiredLabels...)
+		o.options[path] = c.Options
 		return nil
 	}
 
-	if filename != ownerFilename {
+	if !o.isOwnersFileName(filename) {
+		return nil
+	}
+	if preferred := o.preferredOwnersFile(filepath.Dir(path)); preferred != filename {
+		glog.V(2).Infof("Ignoring %s, %s takes precedence", path, preferred)
 		return nil
 	}
 
@@ -135,6 +239,10 @@ func (o *RepoInfo) walkFunc(path string, info os.FileInfo, err error) error {
 
 	if err := yaml.NewYAMLToJSONDecoder(file).Decode(c); err != nil {
 		glog.Errorf("%v", err)
+		if rel, relErr := filepath.Rel(o.projectDir, filepath.Dir(path)); relErr == nil {
+			o.parseErrors[canonicalize(rel)] = fmt.Errorf("%s: %v", filename, err)
+			o.ownersFiles[canonicalize(rel)] = filename
+		}
 		return nil
 	}
 
@@ -147,10 +255,143 @@ func (o *RepoInfo) walkFunc(path string, info os.FileInfo, err error) error {
 	path = canonicalize(path)
 	o.approvers[path] = sets.NewString(c.Approvers...)
 	o.approvers[path].Insert(c.Assignees...)
+	o.approvers[path].Insert(c.EmeritusApprovers...)
+	o.approvers[path].Insert(c.RequiredApprovers...)
+	o.approvers[path].Insert(c.BackupApprovers...)
 	o.reviewers[path] = sets.NewString(c.Reviewers...)
+	o.emeritus[path] = sets.NewString(c.EmeritusApprovers...)
+	o.backup[path] = sets.NewString(c.BackupApprovers...)
+	o.required[path] = sets.NewString(c.RequiredApprovers...)
+	o.labels[path] = sets.NewString(c.Labels...)
+	o.requiredLabels[path] = sets.NewString(c.RequiredLabels...)
+	o.options[path] = c.Options
+	o.ownersFiles[path] = filename
+	o.addFilters(path, c.Filters)
 	return nil
 }
 
+// ownersFileNames returns OwnersFileNames, or OWNERS if unset.
+func (o *RepoInfo) ownersFileNames() []string {
+	if len(o.OwnersFileNames) == 0 {
+		return []string{ownerFilename}
+	}
+	return o.OwnersFileNames
+}
+
+// isOwnersFileName returns true if filename is one of OwnersFileNames.
+func (o *RepoInfo) isOwnersFileName(filename string) bool {
+	for _, name := range o.ownersFileNames() {
+		if filename == name {
+			return true
+		}
+	}
+	return false
+}
+
+// preferredOwnersFile returns the name of the ownership file used in dir,
+// the first of OwnersFileNames that exists.
+func (o *RepoInfo) preferredOwnersFile(dir string) string {
+	for _, name := range o.ownersFileNames() {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.Mode().IsRegular() {
+			return name
+		}
+	}
+	return ""
+}
+
+// OwnersFileName returns the name of the ownership file of an OWNERS key
+// returned by FindApproverOwnersForPath, e.g. OWNERS.
+func (o *RepoInfo) OwnersFileName(key string) string {
+	dir, _ := SplitOwnersKey(key)
+	if name, ok := o.ownersFiles[dir]; ok {
+		return name
+	}
+	return o.ownersFileNames()[0]
+}
+
+// WithOwnersFileNames returns a RepoInfo reading the ownership files of the
+// same checkout with the given names, by precedence, see OwnersFileNames.
+// The checkout is only read again the first time a list of names is used
+// since it was updated.
+func (o *RepoInfo) WithOwnersFileNames(names []string) *RepoInfo {
+	key := strings.Join(names, "\n")
+	if len(names) == 0 || key == strings.Join(o.ownersFileNames(), "\n") {
+		return o
+	}
+	o.viewsLock.Lock()
+	defer o.viewsLock.Unlock()
+	if view, ok := o.views[key]; ok {
+		return view
+	}
+	view := &RepoInfo{
+		BaseDir:              o.BaseDir,
+		EnableMdYaml:         o.EnableMdYaml,
+		UseReviewers:         o.UseReviewers,
+		CaseInsensitivePaths: o.CaseInsensitivePaths,
+		MaxOwnersDepth:       o.MaxOwnersDepth,
+		OwnersFileNames:      names,
+		enabled:              o.enabled,
+		projectDir:           o.projectDir,
+		config:               o.config,
+	}
+	view.loadOwners()
+	if o.views == nil {
+		o.views = map[string]*RepoInfo{}
+	}
+	o.views[key] = view
+	return view
+}
+
+// ListOwnersDirs returns the sorted directories with an ownership file in
+// root and its subdirectories. The root of the repo is "".
+func (o *RepoInfo) ListOwnersDirs(root string) []string {
+	root = canonicalize(root)
+	dirs := sets.NewString()
+	for dir := range o.ownersFiles {
+		if root == baseDirConvention || dir == root || strings.HasPrefix(dir, root+"/") {
+			dirs.Insert(dir)
+		}
+	}
+	return dirs.List()
+}
+
+// ParseErrors returns the errors parsing the ownership files of path's
+// directory and its parents, by directory. The approvers of path may be
+// wrong if any failed to parse, as they are ignored.
+func (o *RepoInfo) ParseErrors(path string) map[string]error {
+	errs := map[string]error{}
+	d, _ := SplitOwnersKey(path)
+	d = canonicalize(d)
+	for {
+		if err, ok := o.parseErrors[d]; ok {
+			errs[d] = err
+		}
+		if d == baseDirConvention {
+			break
+		}
+		d = canonicalize(filepath.Dir(d))
+	}
+	return errs
+}
+
+// addFilters stores the filters of the OWNERS file in dir, sorted by
+// pattern. Invalid patterns are ignored.
+func (o *RepoInfo) addFilters(dir string, filters map[string]ownersFilter) {
+	patterns := sets.NewString()
+	for pattern := range filters {
+		patterns.Insert(pattern)
+	}
+	for _, pattern := range patterns.List() {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			glog.Errorf("Invalid filter %q in %s/%s: %v", pattern, dir, o.OwnersFileName(dir), err)
+			continue
+		}
+		o.filters[dir] = append(o.filters[dir], approversFilter{pattern: pattern, re: re})
+		o.approvers[FilteredOwnersKey(dir, pattern)] = sets.NewString(filters[pattern].Approvers...)
+	}
+}
+
 // decodeAssignmentConfig will parse the yaml header if it exists and unmarshal it into an assignmentConfig.
 // If no yaml header is found, do nothing
 // Returns an error if the file cannot be read or the yaml header is found but cannot be unmarshalled
@@ -182,18 +423,50 @@ func (o *RepoInfo) updateRepoUsers() error {
 	}
 	sha := out
 
-	o.approvers = map[string]sets.String{}
-	o.reviewers = map[string]sets.String{}
-	err = filepath.Walk(o.projectDir, o.walkFunc)
-	if err != nil {
-		glog.Errorf("Got error %v", err)
-	}
+	o.loadOwners()
 	glog.Infof("Loaded config from %s:%s", o.projectDir, sha)
 	glog.V(5).Infof("approvers: %v", o.approvers)
 	glog.V(5).Infof("reviewers: %v", o.reviewers)
 	return nil
 }
 
+// loadOwners reads the ownership files of the checkout in projectDir.
+func (o *RepoInfo) loadOwners() {
+	o.approvers = map[string]sets.String{}
+	o.reviewers = map[string]sets.String{}
+	o.emeritus = map[string]sets.String{}
+	o.backup = map[string]sets.String{}
+	o.required = map[string]sets.String{}
+	o.labels = map[string]sets.String{}
+	o.requiredLabels = map[string]sets.String{}
+	o.filters = map[string][]approversFilter{}
+	o.options = map[string]dirOptions{}
+	o.ownersFiles = map[string]string{}
+	o.parseErrors = map[string]error{}
+	o.viewsLock.Lock()
+	o.views = nil
+	o.viewsLock.Unlock()
+	if err := filepath.Walk(o.projectDir, o.walkFunc); err != nil {
+		glog.Errorf("Got error %v", err)
+	}
+	o.indexLowerDirs()
+}
+
+// indexLowerDirs builds lowerDirs from the OWNERS directories, if
+// CaseInsensitivePaths is set.
+func (o *RepoInfo) indexLowerDirs() {
+	if !o.CaseInsensitivePaths {
+		o.lowerDirs = nil
+		return
+	}
+	o.lowerDirs = map[string]string{}
+	for key := range o.approvers {
+		if d, filter := SplitOwnersKey(key); filter == "" {
+			o.lowerDirs[strings.ToLower(d)] = d
+		}
+	}
+}
+
 // Initialize will initialize the munger
 func (o *RepoInfo) Initialize(config *github.Config) error {
 	o.enabled = true
@@ -254,6 +527,9 @@ func (o *RepoInfo) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&o.BaseDir, "repo-dir", "", "Path to perform checkout of repository")
 	cmd.Flags().BoolVar(&o.EnableMdYaml, "enable-md-yaml", false, "If true, look for assignees in md yaml headers.")
 	cmd.Flags().BoolVar(&o.UseReviewers, "use-reviewers", false, "Use \"reviewers\" rather than \"approvers\" for review")
+	cmd.Flags().BoolVar(&o.CaseInsensitivePaths, "case-insensitive-paths", false, "If true, match files with OWNERS files regardless of case.")
+	cmd.Flags().StringSliceVar(&o.OwnersFileNames, "owners-file-names", []string{ownerFilename}, "Comma separated names of the ownership files, by precedence: only the first one found in a directory is used.")
+	cmd.Flags().IntVar(&o.MaxOwnersDepth, "max-owners-depth", 0, "Maximum number of directories to walk up looking for the OWNERS of a file, the repo root OWNERS are used past it. 0 means no limit.")
 }
 
 // GitCommand will execute the git command with the `args` within the project directory.
@@ -270,10 +546,12 @@ func (o *RepoInfo) gitCommandDir(args []string, cmdDir string) ([]byte, error) {
 
 // findOwnersForPath returns the OWNERS file path furthest down the tree for a specified file
 // By default we use the reviewers section of owners flag but this can be configured by setting approvers to true
-func findOwnersForPath(path string, ownerMap map[string]sets.String) string {
+// maxDepth is the maximum number of directories walked up, see
+// MaxOwnersDepth.
+func findOwnersForPath(path string, ownerMap map[string]sets.String, maxDepth int) string {
 	d := path
 
-	for {
+	for depth := 0; ; depth++ {
 		n, ok := ownerMap[d]
 		if ok && len(n) != 0 {
 			return d
@@ -281,22 +559,97 @@ func findOwnersForPath(path string, ownerMap map[string]sets.String) string {
 		if d == baseDirConvention {
 			break
 		}
+		if tooDeep(path, depth, maxDepth) {
+			d = baseDirConvention
+			continue
+		}
 		d = filepath.Dir(d)
 		d = canonicalize(d)
 	}
 	return ""
 }
 
+// tooDeep returns true if the walk up from path has reached maxDepth
+// directories, in which case the walk should skip to the repo root.
+func tooDeep(path string, depth, maxDepth int) bool {
+	if maxDepth <= 0 || depth < maxDepth {
+		return false
+	}
+	glog.Warningf("Reached the maximum depth %d looking for the OWNERS of %v, using the repo root OWNERS", maxDepth, path)
+	return true
+}
+
 // FindApproversForPath returns the OWNERS file path furthest down the tree for a specified file
-// that contains an approvers section
+// that contains an approvers section. If a filter of that OWNERS file
+// matches the file, the key of the filter is returned (see
+// FilteredOwnersKey), so that Approvers and LeafApprovers include the
+// approvers of the filter.
 func (o *RepoInfo) FindApproverOwnersForPath(path string) string {
-	return findOwnersForPath(path, o.approvers)
+	d := path
+
+	for depth := 0; ; depth++ {
+		// d and path may differ in case, see CaseInsensitivePaths.
+		rel := path
+		if d != baseDirConvention && len(path) > len(d) {
+			rel = path[len(d)+1:]
+		}
+		d = o.ownersDir(d)
+		for _, filter := range o.filters[d] {
+			key := FilteredOwnersKey(d, filter.pattern)
+			if filter.re.MatchString(rel) && len(o.approvers[key]) != 0 {
+				return key
+			}
+		}
+		if len(o.approvers[d]) != 0 {
+			return d
+		}
+		if d == baseDirConvention {
+			break
+		}
+		if tooDeep(path, depth, o.MaxOwnersDepth) {
+			d = baseDirConvention
+			continue
+		}
+		d = filepath.Dir(d)
+		d = canonicalize(d)
+	}
+	return ""
+}
+
+// ownersDir returns the OWNERS directory matching dir. Unless
+// CaseInsensitivePaths is set, that's dir itself.
+func (o *RepoInfo) ownersDir(dir string) string {
+	if !o.CaseInsensitivePaths {
+		return dir
+	}
+	if _, ok := o.approvers[dir]; ok {
+		return dir
+	}
+	if actual, ok := o.lowerDirs[strings.ToLower(dir)]; ok {
+		return actual
+	}
+	return dir
+}
+
+// peopleForKey is like peopleForPath, but also accepts the keys of
+// filtered OWNERS files, whose people are added to the people of the
+// directory.
+func (o *RepoInfo) peopleForKey(key string, people map[string]sets.String, leafOnly bool) sets.String {
+	dir, filter := SplitOwnersKey(key)
+	if filter == "" {
+		return peopleForPath(key, people, o.options, leafOnly, o.EnableMdYaml)
+	}
+	out := sets.NewString().Union(people[key])
+	if leafOnly && out.Len() > 0 {
+		return out
+	}
+	return out.Union(peopleForPath(dir, people, o.options, leafOnly, o.EnableMdYaml))
 }
 
 // FindReviewersForPath returns the OWNERS file path furthest down the tree for a specified file
 // that contains a reviewers section
 func (o *RepoInfo) FindReviewersForPath(path string) string {
-	return findOwnersForPath(path, o.reviewers)
+	return findOwnersForPath(path, o.reviewers, o.MaxOwnersDepth)
 }
 
 // peopleForPath returns a set of users who are assignees to the
@@ -304,7 +657,8 @@ func (o *RepoInfo) FindReviewersForPath(path string) string {
 // and not directory as the final directory will be discounted if enableMdYaml is true
 // leafOnly indicates whether only the OWNERS deepest in the tree (closest to the file)
 // should be returned or if all OWNERS in filepath should be returned
-func peopleForPath(path string, people map[string]sets.String, leafOnly bool, enableMdYaml bool) sets.String {
+// The walk up the tree stops at the first directory with the no_parent_owners option.
+func peopleForPath(path string, people map[string]sets.String, options map[string]dirOptions, leafOnly bool, enableMdYaml bool) sets.String {
 	d := path
 	if !enableMdYaml {
 		// if path is a directory, this will remove the leaf directory, and returns "." for topmost dir
@@ -321,6 +675,9 @@ func peopleForPath(path string, people map[string]sets.String, leafOnly bool, en
 				break
 			}
 		}
+		if options[d].NoParentOwners {
+			break
+		}
 		if d == baseDirConvention {
 			break
 		}
@@ -330,19 +687,79 @@ func peopleForPath(path string, people map[string]sets.String, leafOnly bool, en
 	return out
 }
 
+// IsNoParentOwners returns true if the OWNERS file in the given directory
+// prevents approvers and reviewers from being inherited from its parents.
+func (o *RepoInfo) IsNoParentOwners(path string) bool {
+	if _, filter := SplitOwnersKey(path); filter != "" {
+		// filters always inherit from their directory
+		return false
+	}
+	return o.options[path].NoParentOwners
+}
+
+// RequiredApprovals returns the number of approvers needed to approve the
+// OWNERS file in the given directory.
+func (o *RepoInfo) RequiredApprovals(path string) int {
+	dir, _ := SplitOwnersKey(path)
+	if required := o.options[dir].RequiredApprovals; required > 1 {
+		return required
+	}
+	return 1
+}
+
 // LeafApprovers returns a set of users who are the closest approvers to the
 // requested file. If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will only return user2 for the path pkg/util/sets/file.go
 func (o *RepoInfo) LeafApprovers(path string) sets.String {
-	return peopleForPath(path, o.approvers, true, o.EnableMdYaml)
+	return o.peopleForKey(path, o.approvers, true)
 }
 
 // Approvers returns ALL of the users who are approvers for the
 // requested file (including approvers in parent dirs' OWNERS).
 // If pkg/OWNERS has user1 and pkg/util/OWNERS has user2 this
 // will return both user1 and user2 for the path pkg/util/sets/file.go
+// unless pkg/util/OWNERS sets the no_parent_owners option.
 func (o *RepoInfo) Approvers(path string) sets.String {
-	return peopleForPath(path, o.approvers, false, o.EnableMdYaml)
+	return o.peopleForKey(path, o.approvers, false)
+}
+
+// EmeritusApprovers returns ALL of the users who are listed as emeritus
+// approvers for the requested file (including parent dirs' OWNERS). Emeritus
+// approvers are also returned by Approvers, as they can still approve.
+func (o *RepoInfo) EmeritusApprovers(path string) sets.String {
+	return o.peopleForKey(path, o.emeritus, false)
+}
+
+// BackupApprovers returns ALL of the users who are listed as backup
+// approvers for the requested file (including parent dirs' OWNERS). Backup
+// approvers are also returned by Approvers, as they can still approve.
+func (o *RepoInfo) BackupApprovers(path string) sets.String {
+	return o.peopleForKey(path, o.backup, false)
+}
+
+// PrimaryApprovers returns the approvers of the requested file who aren't
+// backup approvers, i.e. who should be suggested first.
+func (o *RepoInfo) PrimaryApprovers(path string) sets.String {
+	return o.Approvers(path).Difference(o.BackupApprovers(path))
+}
+
+// RequiredApprovers returns ALL of the users who must approve the
+// requested file (including parent dirs' OWNERS). Required approvers are
+// also returned by Approvers.
+func (o *RepoInfo) RequiredApprovers(path string) sets.String {
+	return o.peopleForKey(path, o.required, false)
+}
+
+// Labels returns ALL of the labels listed in the OWNERS files of the
+// requested file (including parent dirs' OWNERS).
+func (o *RepoInfo) Labels(path string) sets.String {
+	return o.peopleForKey(path, o.labels, false)
+}
+
+// RequiredLabels returns ALL of the required labels listed in the OWNERS
+// files of the requested file (including parent dirs' OWNERS).
+func (o *RepoInfo) RequiredLabels(path string) sets.String {
+	return o.peopleForKey(path, o.requiredLabels, false)
 }
 
 // LeafReviewers returns a set of users who are the closest reviewers to the
@@ -352,7 +769,7 @@ func (o *RepoInfo) LeafReviewers(path string) sets.String {
 	if !o.UseReviewers {
 		return o.LeafApprovers(path)
 	}
-	return peopleForPath(path, o.reviewers, true, o.EnableMdYaml)
+	return o.peopleForKey(path, o.reviewers, true)
 }
 
 // Reviewers returns ALL of the users who are reviewers for the
@@ -363,5 +780,5 @@ func (o *RepoInfo) Reviewers(path string) sets.String {
 	if !o.UseReviewers {
 		return o.Approvers(path)
 	}
-	return peopleForPath(path, o.reviewers, false, o.EnableMdYaml)
+	return o.peopleForKey(path, o.reviewers, false)
 }
@@ -18,7 +18,11 @@ package features
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"k8s.io/kubernetes/pkg/util/sets"
@@ -104,6 +108,438 @@ func TestGetApprovers(t *testing.T) {
 	}
 }
 
+func TestNoParentOwners(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.options = map[string]dirOptions{leafDir: {NoParentOwners: true}}
+	leafFile := filepath.Join(leafDir, "testFile.md")
+	baseFile := filepath.Join(baseDir, "testFile.md")
+
+	if !testRepo.IsNoParentOwners(leafDir) {
+		t.Errorf("Expected %v to have the no_parent_owners option", leafDir)
+	}
+	if testRepo.IsNoParentOwners(baseDir) {
+		t.Errorf("Expected %v to not have the no_parent_owners option", baseDir)
+	}
+	if found := testRepo.Approvers(leafFile); !found.Equal(testRepo.approvers[leafDir]) {
+		t.Errorf("Expected approvers %v for %v, found %v", testRepo.approvers[leafDir], leafFile, found)
+	}
+	if found := testRepo.Approvers(baseFile); !found.Equal(testRepo.approvers[baseDir]) {
+		t.Errorf("Expected approvers %v for %v, found %v", testRepo.approvers[baseDir], baseFile, found)
+	}
+}
+
+func TestEmeritusApprovers(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.emeritus = map[string]sets.String{baseDir: sets.NewString("Eve")}
+	leafFile := filepath.Join(leafDir, "testFile.md")
+
+	if found := testRepo.EmeritusApprovers(leafFile); !found.Equal(sets.NewString("Eve")) {
+		t.Errorf("Expected emeritus approvers %v for %v, found %v", sets.NewString("Eve"), leafFile, found)
+	}
+	testRepo.options = map[string]dirOptions{leafDir: {NoParentOwners: true}}
+	if found := testRepo.EmeritusApprovers(leafFile); found.Len() != 0 {
+		t.Errorf("Expected no emeritus approvers for %v, found %v", leafFile, found)
+	}
+}
+
+func TestBackupApprovers(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.backup = map[string]sets.String{baseDir: sets.NewString("Bob")}
+	leafFile := filepath.Join(leafDir, "testFile.md")
+
+	if found := testRepo.BackupApprovers(leafFile); !found.Equal(sets.NewString("Bob")) {
+		t.Errorf("Expected backup approvers %v for %v, found %v", sets.NewString("Bob"), leafFile, found)
+	}
+	if expected, found := sets.NewString("Alice", "Carl", "Dave"), testRepo.PrimaryApprovers(leafFile); !found.Equal(expected) {
+		t.Errorf("Expected primary approvers %v for %v, found %v", expected, leafFile, found)
+	}
+	if !testRepo.Approvers(leafFile).Has("Bob") {
+		t.Errorf("Expected backup approvers to still be approvers of %v", leafFile)
+	}
+}
+
+func TestListOwnersDirs(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.ownersFiles = map[string]string{
+		"":      "OWNERS",
+		"a":     "OWNERS",
+		"a/b":   "OWNERS",
+		"a/b/c": "MAINTAINERS",
+		"ab":    "OWNERS",
+	}
+	tests := []struct {
+		root     string
+		expected []string
+	}{
+		{root: "", expected: []string{"", "a", "a/b", "a/b/c", "ab"}},
+		{root: "a", expected: []string{"a", "a/b", "a/b/c"}},
+		{root: "a/b/", expected: []string{"a/b", "a/b/c"}},
+		{root: "missing", expected: []string{}},
+	}
+	for _, test := range tests {
+		if found := testRepo.ListOwnersDirs(test.root); !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("Expected OWNERS directories %v in %q, found %v", test.expected, test.root, found)
+		}
+	}
+}
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	tests := []struct {
+		testName             string
+		caseInsensitivePaths bool
+		path                 string
+		expectedOwners       string
+	}{
+		{
+			testName:       "Same case",
+			path:           "a/b/c/testFile.go",
+			expectedOwners: leafDir,
+		},
+		{
+			testName:       "Mixed case, case-sensitive",
+			path:           "A/b/C/testFile.go",
+			expectedOwners: baseDir,
+		},
+		{
+			testName:             "Mixed case, case-insensitive",
+			caseInsensitivePaths: true,
+			path:                 "A/b/C/testFile.go",
+			expectedOwners:       leafDir,
+		},
+		{
+			testName:             "Other mixed case, case-insensitive",
+			caseInsensitivePaths: true,
+			path:                 "a/B/c/d/testFile.go",
+			expectedOwners:       leafDir,
+		},
+		{
+			testName:             "Mixed case filter, case-insensitive",
+			caseInsensitivePaths: true,
+			path:                 "A/B/C/BUILD",
+			expectedOwners:       FilteredOwnersKey(leafDir, "^BUILD$"),
+		},
+	}
+	for _, test := range tests {
+		testRepo := getTestRepo()
+		testRepo.CaseInsensitivePaths = test.caseInsensitivePaths
+		testRepo.filters = map[string][]approversFilter{}
+		testRepo.addFilters(leafDir, map[string]ownersFilter{"^BUILD$": {Approvers: []string{"Builder"}}})
+		testRepo.indexLowerDirs()
+		if found := testRepo.FindApproverOwnersForPath(test.path); found != test.expectedOwners {
+			t.Errorf("Failed for test %v. Expected OWNERS %q for %v, found %q", test.testName, test.expectedOwners, test.path, found)
+		}
+	}
+}
+
+func TestMaxOwnersDepth(t *testing.T) {
+	deepPath := strings.Repeat("d/", 50) + "testFile.go"
+	tests := []struct {
+		testName       string
+		maxDepth       int
+		path           string
+		expectedOwners string
+	}{
+		{
+			testName:       "No limit",
+			path:           leafDir + "/" + deepPath,
+			expectedOwners: leafDir,
+		},
+		{
+			testName:       "Within the limit",
+			maxDepth:       10,
+			path:           leafDir + "/testFile.go",
+			expectedOwners: leafDir,
+		},
+		{
+			testName:       "Past the limit",
+			maxDepth:       10,
+			path:           leafDir + "/" + deepPath,
+			expectedOwners: baseDir,
+		},
+	}
+	for _, test := range tests {
+		testRepo := getTestRepo()
+		testRepo.MaxOwnersDepth = test.maxDepth
+		testRepo.reviewers = testRepo.approvers
+		if found := testRepo.FindApproverOwnersForPath(test.path); found != test.expectedOwners {
+			t.Errorf("Failed for test %v. Expected approvers OWNERS %q, found %q", test.testName, test.expectedOwners, found)
+		}
+		if found := testRepo.FindReviewersForPath(test.path); found != test.expectedOwners {
+			t.Errorf("Failed for test %v. Expected reviewers OWNERS %q, found %q", test.testName, test.expectedOwners, found)
+		}
+	}
+}
+
+func TestOwnersFileNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "owners")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"legacy/MAINTAINERS": "approvers:\n- Legacy\n",
+		"both/MAINTAINERS":   "approvers:\n- Legacy\n",
+		"both/OWNERS":        "approvers:\n- Owner\n",
+		"owners/OWNERS":      "approvers:\n- Owner\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %v: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %v: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		testName          string
+		dir               string
+		expectedApprovers sets.String
+		expectedFileName  string
+	}{
+		{
+			testName:          "Only the legacy file",
+			dir:               "legacy",
+			expectedApprovers: sets.NewString("Legacy"),
+			expectedFileName:  "MAINTAINERS",
+		},
+		{
+			testName:          "Both files, the first wins",
+			dir:               "both",
+			expectedApprovers: sets.NewString("Owner"),
+			expectedFileName:  "OWNERS",
+		},
+		{
+			testName:          "Only the new file",
+			dir:               "owners",
+			expectedApprovers: sets.NewString("Owner"),
+			expectedFileName:  "OWNERS",
+		},
+	}
+
+	repo := &RepoInfo{projectDir: dir, OwnersFileNames: []string{"OWNERS", "MAINTAINERS"}}
+	repo.loadOwners()
+	for _, test := range tests {
+		if found := repo.LeafApprovers(test.dir); !test.expectedApprovers.Equal(found) {
+			t.Errorf("Failed for test %v. Expected approvers %v, found %v", test.testName, test.expectedApprovers, found)
+		}
+		if found := repo.OwnersFileName(test.dir); found != test.expectedFileName {
+			t.Errorf("Failed for test %v. Expected ownership file %q, found %q", test.testName, test.expectedFileName, found)
+		}
+	}
+
+	// Only OWNERS is read by default.
+	repo = &RepoInfo{projectDir: dir}
+	repo.loadOwners()
+	if found := repo.LeafApprovers("legacy"); found.Len() != 0 {
+		t.Errorf("Expected the legacy file to be ignored by default. Found approvers %v", found)
+	}
+
+	// Evaluations can read the legacy files of the same checkout.
+	view := repo.WithOwnersFileNames([]string{"MAINTAINERS", "OWNERS"})
+	if found, expected := view.LeafApprovers("both"), sets.NewString("Legacy"); !expected.Equal(found) {
+		t.Errorf("Expected the first of the given names to win. Found approvers %v", found)
+	}
+	if found := view.OwnersFileName("both"); found != "MAINTAINERS" {
+		t.Errorf("Expected ownership file %q, found %q", "MAINTAINERS", found)
+	}
+	if found := repo.LeafApprovers("legacy"); found.Len() != 0 {
+		t.Errorf("Expected the repo to still ignore the legacy file. Found approvers %v", found)
+	}
+	if repo.WithOwnersFileNames([]string{"MAINTAINERS", "OWNERS"}) != view {
+		t.Errorf("Expected the checkout to be read once for the same names")
+	}
+	if repo.WithOwnersFileNames([]string{"OWNERS"}) != repo {
+		t.Errorf("Expected the repo itself for its own names")
+	}
+}
+
+func TestCaseInsensitivePathsLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "owners")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Docs", "OWNERS")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create %v: %v", filepath.Dir(path), err)
+	}
+	if err := ioutil.WriteFile(path, []byte("approvers:\n- Writer\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %v: %v", path, err)
+	}
+
+	repo := &RepoInfo{projectDir: dir, CaseInsensitivePaths: true}
+	repo.loadOwners()
+	// Lookups only read the maps, so they can run concurrently.
+	done := make(chan string)
+	for i := 0; i < 2; i++ {
+		go func() { done <- repo.FindApproverOwnersForPath("docs/README.md") }()
+	}
+	for i := 0; i < 2; i++ {
+		if found := <-done; found != "Docs" {
+			t.Errorf("Expected the OWNERS of %q, found %q", "Docs", found)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "owners")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"OWNERS":        "approvers:\n- Root\n",
+		"a/OWNERS":      "approvers: [Alice\n",
+		"b/OWNERS":      "approvers:\n- Bob\n",
+		"c/MAINTAINERS": "approvers: [Carol\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %v: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %v: %v", path, err)
+		}
+	}
+
+	repo := &RepoInfo{projectDir: dir, OwnersFileNames: []string{"OWNERS", "MAINTAINERS"}}
+	repo.loadOwners()
+	tests := []struct {
+		path         string
+		expectedDirs []string
+	}{
+		{path: "a/c/file.go", expectedDirs: []string{"a"}},
+		{path: "a", expectedDirs: []string{"a"}},
+		{path: "b/file.go", expectedDirs: []string{}},
+		{path: "file.go", expectedDirs: []string{}},
+	}
+	for _, test := range tests {
+		found := []string{}
+		for dir := range repo.ParseErrors(test.path) {
+			found = append(found, dir)
+		}
+		if !reflect.DeepEqual(found, test.expectedDirs) {
+			t.Errorf("Expected parse errors in %v for %q, found %v", test.expectedDirs, test.path, found)
+		}
+	}
+	if found := repo.FindApproverOwnersForPath("a/file.go"); found != "" {
+		t.Errorf("Expected the OWNERS file failing to parse to be ignored. Found %q", found)
+	}
+	if found := repo.OwnersFileName("c"); found != "MAINTAINERS" {
+		t.Errorf("Expected the name of the file failing to parse, %q. Found %q", "MAINTAINERS", found)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.filters = map[string][]approversFilter{}
+	testRepo.addFilters(leafDir, map[string]ownersFilter{
+		`\.go$`:   {Approvers: []string{"Gopher"}},
+		`^BUILD$`: {Approvers: []string{"Builder"}},
+		`\.md$`:   {},
+		`(`:       {Approvers: []string{"Invalid"}},
+	})
+	goKey := FilteredOwnersKey(leafDir, `\.go$`)
+	buildKey := FilteredOwnersKey(leafDir, `^BUILD$`)
+
+	tests := []struct {
+		testName          string
+		path              string
+		expectedOwners    string
+		expectedApprovers sets.String
+		expectedLeaf      sets.String
+	}{
+		{
+			testName:          "Go File",
+			path:              filepath.Join(leafDir, "file.go"),
+			expectedOwners:    goKey,
+			expectedApprovers: sets.NewString("Gopher", "Carl", "Dave", "Alice", "Bob"),
+			expectedLeaf:      sets.NewString("Gopher"),
+		},
+		{
+			testName:          "BUILD File",
+			path:              filepath.Join(leafDir, "BUILD"),
+			expectedOwners:    buildKey,
+			expectedApprovers: sets.NewString("Builder", "Carl", "Dave", "Alice", "Bob"),
+			expectedLeaf:      sets.NewString("Builder"),
+		},
+		{
+			testName:          "Filter Without Approvers",
+			path:              filepath.Join(leafDir, "README.md"),
+			expectedOwners:    leafDir,
+			expectedApprovers: sets.NewString("Carl", "Dave", "Alice", "Bob"),
+			expectedLeaf:      sets.NewString("Carl", "Dave"),
+		},
+	}
+
+	for _, test := range tests {
+		key := testRepo.FindApproverOwnersForPath(test.path)
+		if key != test.expectedOwners {
+			t.Errorf("%s: expected owners %q, found %q", test.testName, test.expectedOwners, key)
+		}
+		if found := testRepo.Approvers(key); !found.Equal(test.expectedApprovers) {
+			t.Errorf("%s: expected approvers %v, found %v", test.testName, test.expectedApprovers, found)
+		}
+		if found := testRepo.LeafApprovers(key); !found.Equal(test.expectedLeaf) {
+			t.Errorf("%s: expected leaf approvers %v, found %v", test.testName, test.expectedLeaf, found)
+		}
+	}
+	if dir, filter := SplitOwnersKey(goKey); dir != leafDir || filter != `\.go$` {
+		t.Errorf("SplitOwnersKey(%q) = %q, %q", goKey, dir, filter)
+	}
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.options = map[string]dirOptions{leafDir: {RequiredApprovals: 2}}
+
+	if found := testRepo.RequiredApprovals(leafDir); found != 2 {
+		t.Errorf("Expected 2 required approvals for %v, found %v", leafDir, found)
+	}
+	if found := testRepo.RequiredApprovals(baseDir); found != 1 {
+		t.Errorf("Expected 1 required approval for %v, found %v", baseDir, found)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.labels = map[string]sets.String{
+		baseDir: sets.NewString("kind/foo"),
+		leafDir: sets.NewString("sig/bar"),
+	}
+	leafFile := filepath.Join(leafDir, "testFile.md")
+
+	if found, expected := testRepo.Labels(leafFile), sets.NewString("kind/foo", "sig/bar"); !found.Equal(expected) {
+		t.Errorf("Expected labels %v for %v, found %v", expected, leafFile, found)
+	}
+
+	testRepo.requiredLabels = map[string]sets.String{
+		leafDir: sets.NewString("api-review"),
+	}
+	if found, expected := testRepo.RequiredLabels(leafFile), sets.NewString("api-review"); !found.Equal(expected) {
+		t.Errorf("Expected required labels %v for %v, found %v", expected, leafFile, found)
+	}
+}
+
+func TestRequiredApprovers(t *testing.T) {
+	testRepo := getTestRepo()
+	testRepo.required = map[string]sets.String{
+		baseDir: sets.NewString("security"),
+		leafDir: sets.NewString("leafLead"),
+	}
+	leafFile := filepath.Join(leafDir, "testFile.md")
+
+	if found, expected := testRepo.RequiredApprovers(leafFile), sets.NewString("security", "leafLead"); !found.Equal(expected) {
+		t.Errorf("Expected required approvers %v for %v, found %v", expected, leafFile, found)
+	}
+	if found := testRepo.RequiredApprovers("a/b/testFile.md"); !found.Equal(sets.NewString("security")) {
+		t.Errorf("Expected only the root required approvers, found %v", found)
+	}
+}
+
 func TestCanonical(t *testing.T) {
 
 	tests := []struct {
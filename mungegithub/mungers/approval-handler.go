@@ -17,9 +17,13 @@ limitations under the License.
 package mungers
 
 import (
+	"strings"
+	"time"
+
 	githubapi "github.com/google/go-github/github"
 	"github.com/spf13/cobra"
 
+	"k8s.io/kubernetes/pkg/util/sets"
 	"k8s.io/test-infra/mungegithub/features"
 	"k8s.io/test-infra/mungegithub/github"
 	"k8s.io/test-infra/mungegithub/mungers/approvers"
@@ -36,7 +40,10 @@ const (
 // ApprovalHandler will try to add "approved" label once
 // all files of change has been approved by approvers.
 type ApprovalHandler struct {
-	features *features.Features
+	features            *features.Features
+	baseURL             string
+	selfApprovalAllowed bool
+	notificationName    string
 }
 
 func init() {
@@ -62,7 +69,11 @@ func (h *ApprovalHandler) Initialize(config *github.Config, features *features.F
 func (*ApprovalHandler) EachLoop() error { return nil }
 
 // AddFlags will add any request flags to the cobra `cmd`
-func (*ApprovalHandler) AddFlags(cmd *cobra.Command, config *github.Config) {}
+func (h *ApprovalHandler) AddFlags(cmd *cobra.Command, config *github.Config) {
+	cmd.Flags().StringVar(&h.baseURL, "approvers-base-url", "https://github.com", "The GitHub (Enterprise) host used to link OWNERS files")
+	cmd.Flags().BoolVar(&h.selfApprovalAllowed, "approvers-allow-self-approval", true, "If true, the PR author implicitly approves the files they own")
+	cmd.Flags().StringVar(&h.notificationName, "approvers-notification-name", approvers.ApprovalNotificationName, "The name of the approval notification, distinct for each approval bot of the repo")
+}
 
 // Munge is the workhorse the will actually make updates to the PR
 // The algorithm goes as:
@@ -97,7 +108,12 @@ func (h *ApprovalHandler) Munge(obj *github.MungeObject) {
 		approvers.NewOwners(
 			filenames,
 			approvers.NewRepoAlias(h.features.Repos, *h.features.Aliases),
-			int64(*obj.Issue.Number)))
+			approvers.SeedForPR(obj.Org(), obj.Project(), *obj.Issue.Number)))
+	approversHandler.BaseURL = h.baseURL
+	approversHandler.SelfApprovalAllowed = h.selfApprovalAllowed
+	if obj.Issue.User != nil && obj.Issue.User.Login != nil {
+		approversHandler.ExcludedApprovers = sets.NewString(*obj.Issue.User.Login)
+	}
 	addApprovers(&approversHandler, comments)
 	// Author implicitly approves their own PR
 	if obj.Issue.User != nil && obj.Issue.User.Login != nil {
@@ -106,7 +122,7 @@ func (h *ApprovalHandler) Munge(obj *github.MungeObject) {
 			// Append extra # so that it doesn't reload the page.
 			url = *obj.Issue.HTMLURL + "#"
 		}
-		approversHandler.AddAuthorSelfApprover(*obj.Issue.User.Login, url)
+		approversHandler.ApplyAuthorSelfApproval(*obj.Issue.User.Login, url)
 	}
 
 	for _, user := range obj.Issue.Assignees {
@@ -114,12 +130,23 @@ func (h *ApprovalHandler) Munge(obj *github.MungeObject) {
 			approversHandler.AddAssignees(*user.Login)
 		}
 	}
+	approversHandler.AddLabels()
+	for _, label := range obj.Issue.Labels {
+		if label.Name != nil {
+			approversHandler.AddLabels(*label.Name)
+		}
+	}
 
-	notificationMatcher := c.MungerNotificationName(approvers.ApprovalNotificationName)
+	notificationName := h.notificationName
+	if notificationName == "" {
+		notificationName = approvers.ApprovalNotificationName
+	}
+	notificationMatcher := c.MungerNotificationName(notificationName)
 
 	latestNotification := c.FilterComments(comments, notificationMatcher).GetLast()
 	latestApprove := getApproveComments(comments).GetLast()
-	newMessage := h.updateNotification(obj.Org(), obj.Project(), latestNotification, latestApprove, approversHandler)
+	branch, _ := obj.Branch()
+	newMessage := h.updateNotification(obj.Org(), obj.Project(), branch, latestNotification, latestApprove, approversHandler)
 	if newMessage != nil {
 		if latestNotification != nil {
 			obj.DeleteComment(latestNotification)
@@ -160,14 +187,14 @@ func getApproveComments(comments []*githubapi.IssueComment) c.FilteredComments {
 	return c.FilterComments(comments, c.And{c.HumanActor(), c.Or{approverMatcher, lgtmMatcher}})
 }
 
-func (h *ApprovalHandler) updateNotification(org, project string, latestNotification, latestApprove *githubapi.IssueComment, approversHandler approvers.Approvers) *string {
+func (h *ApprovalHandler) updateNotification(org, project, branch string, latestNotification, latestApprove *githubapi.IssueComment, approversHandler approvers.Approvers) *string {
 	if latestNotification != nil && (latestApprove == nil || latestApprove.CreatedAt.Before(*latestNotification.CreatedAt)) {
 		// if we have an existing notification AND
 		// the latestApprove happened before we updated
 		// the notification, we do NOT need to update
 		return nil
 	}
-	return approvers.GetMessage(approversHandler, org, project)
+	return approvers.GetMessageWithOptions(approversHandler, org, project, branch, approvers.MessageOptions{NotificationName: h.notificationName})
 }
 
 // addApprovers iterates through the list of comments on a PR
@@ -188,21 +215,35 @@ func addApprovers(approversHandler *approvers.Approvers, comments []*githubapi.I
 
 			if cmd.Arguments == cancel {
 				approversHandler.RemoveApprover(*comment.User.Login)
+			} else if args := strings.Fields(cmd.Arguments); len(args) > 1 && args[0] == cancel {
+				// "/approve cancel path..." only cancels the given directories
+				for _, path := range args[1:] {
+					approversHandler.RemoveApproverForPath(*comment.User.Login, path)
+				}
 			} else {
 				url := ""
 				if comment.HTMLURL != nil {
 					url = *comment.HTMLURL
 				}
 
+				at := time.Time{}
+				if comment.CreatedAt != nil {
+					at = *comment.CreatedAt
+				}
+
 				if cmd.Name == approveCommand {
-					approversHandler.AddApprover(
+					approversHandler.AddApproverAt(
 						*comment.User.Login,
 						url,
+						"",
+						at,
 					)
 				} else {
-					approversHandler.AddLGTMer(
+					approversHandler.AddLGTMerAt(
 						*comment.User.Login,
 						url,
+						"",
+						at,
 					)
 				}
 
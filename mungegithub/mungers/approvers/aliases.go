@@ -0,0 +1,101 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/test-infra/mungegithub/features"
+)
+
+const ownersAliasesFileName = "OWNERS_ALIASES"
+
+// githubLoginRegex matches names that can be github logins. Anything else
+// listed in an alias has to be another alias.
+var githubLoginRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
+
+type ownersAliases struct {
+	Aliases map[string][]string `json:"aliases"`
+}
+
+// LoadOwnersAliases reads and parses the OWNERS_ALIASES file at path.
+func LoadOwnersAliases(path string) (*features.Aliases, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %v", ownersAliasesFileName, err)
+	}
+	return ParseOwnersAliases(data)
+}
+
+// ParseOwnersAliases parses the content of an OWNERS_ALIASES file, mapping
+// aliases to lists of members. An alias can list another alias, which is
+// expanded, but that alias must only list logins. Self-referencing aliases,
+// cycles of aliases and members that are neither logins nor aliases are
+// rejected.
+func ParseOwnersAliases(data []byte) (*features.Aliases, error) {
+	parsed := ownersAliases{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("Failed to decode %s: %v", ownersAliasesFileName, err)
+	}
+
+	if cycle := features.FindAliasCycle(parsed.Aliases); cycle != nil {
+		if len(cycle) == 2 {
+			return nil, fmt.Errorf("Alias %q references itself", cycle[0])
+		}
+		return nil, fmt.Errorf("Circular alias definition in %s: %s", ownersAliasesFileName, strings.Join(cycle, " -> "))
+	}
+
+	// Check the aliases in order, so that the same error is returned
+	// every time.
+	aliases := []string{}
+	for alias := range parsed.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	aliasMap := map[string][]string{}
+	for _, alias := range aliases {
+		members := parsed.Aliases[alias]
+		expanded := sets.NewString()
+		for _, member := range members {
+			nested, ok := parsed.Aliases[member]
+			if !ok {
+				if !githubLoginRegex.MatchString(member) {
+					return nil, fmt.Errorf("Alias %q references unknown alias %q", alias, member)
+				}
+				expanded.Insert(member)
+				continue
+			}
+			for _, nestedMember := range nested {
+				if _, ok := parsed.Aliases[nestedMember]; ok {
+					return nil, fmt.Errorf("Alias %q references %q through %q, only one level of nesting is allowed", alias, nestedMember, member)
+				}
+				if !githubLoginRegex.MatchString(nestedMember) {
+					return nil, fmt.Errorf("Alias %q references unknown alias %q", member, nestedMember)
+				}
+			}
+			expanded.Insert(nested...)
+		}
+		aliasMap[alias] = expanded.List()
+	}
+	return features.NewAliases(aliasMap), nil
+}
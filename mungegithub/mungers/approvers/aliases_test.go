@@ -0,0 +1,135 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+func TestParseOwnersAliases(t *testing.T) {
+	tests := []struct {
+		testName    string
+		data        string
+		toExpand    sets.String
+		expected    sets.String
+		expectError bool
+		// expectedError is the expected error message, if set.
+		expectedError string
+	}{
+		{
+			testName: "Valid aliases",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+    - Bob
+  sig-auth:
+    - Bob
+    - Carol`,
+			toExpand: sets.NewString("sig-node", "sig-auth", "Dave"),
+			expected: sets.NewString("Alice", "Bob", "Carol", "Dave"),
+		},
+		{
+			testName: "Alias referencing another alias",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+  sig-node-leads:
+    - sig-node
+    - Bob`,
+			toExpand: sets.NewString("sig-node-leads"),
+			expected: sets.NewString("Alice", "Bob"),
+		},
+		{
+			testName: "Self-referencing alias",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+    - sig-node`,
+			expectError: true,
+		},
+		{
+			testName: "Cyclic aliases",
+			data: `
+aliases:
+  sig-node:
+    - sig-auth
+  sig-auth:
+    - sig-node`,
+			expectError:   true,
+			expectedError: "Circular alias definition in OWNERS_ALIASES: sig-auth -> sig-node -> sig-auth",
+		},
+		{
+			testName: "Cycle of three aliases",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+    - sig-auth
+  sig-auth:
+    - sig-apps
+  sig-apps:
+    - sig-node`,
+			expectError:   true,
+			expectedError: "Circular alias definition in OWNERS_ALIASES: sig-apps -> sig-node -> sig-auth -> sig-apps",
+		},
+		{
+			testName: "Two levels of nesting",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+  sig-node-leads:
+    - sig-node
+  sig-leads:
+    - sig-node-leads`,
+			expectError: true,
+		},
+		{
+			testName: "Unknown alias",
+			data: `
+aliases:
+  sig-node:
+    - Alice
+    - team/unknown`,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		aliases, err := ParseOwnersAliases([]byte(test.data))
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Failed for test %v.  Expected an error", test.testName)
+			} else if test.expectedError != "" && err.Error() != test.expectedError {
+				t.Errorf("Failed for test %v.  Expected error %q. Found %q", test.testName, test.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed for test %v.  Unexpected error: %v", test.testName, err)
+			continue
+		}
+		if expanded := aliases.Expand(test.toExpand); !expanded.Equal(test.expected) {
+			t.Errorf("Failed for test %v.  Expected: %v. Found %v", test.testName, test.expected, expanded)
+		}
+	}
+}
@@ -17,11 +17,17 @@ limitations under the License.
 package approvers
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"reflect"
 
 	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/test-infra/mungegithub/features"
 )
 
 func TestUnapprovedFiles(t *testing.T) {
@@ -104,7 +110,7 @@ func TestUnapprovedFiles(t *testing.T) {
 	for _, test := range tests {
 		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
 		for approver := range test.currentlyApproved {
-			testApprovers.AddApprover(approver, "REFERENCE")
+			testApprovers.AddApprover(approver, "REFERENCE", "")
 		}
 		calculated := testApprovers.UnapprovedFiles()
 		if !test.expectedUnapproved.Equal(calculated) {
@@ -145,33 +151,33 @@ func TestGetFiles(t *testing.T) {
 			testName:          "Single Root File PR Approved",
 			filenames:         []string{"kubernetes.go"},
 			currentlyApproved: sets.NewString(rootApprovers.List()[0]),
-			expectedFiles:     []File{ApprovedFile{"", sets.NewString(rootApprovers.List()[0]), "org", "project"}},
+			expectedFiles:     []File{ApprovedFile{"", sets.NewString(rootApprovers.List()[0]), "", "", "org", "project", ""}},
 		},
 		{
 			testName:          "Single File PR in B No One Approved",
 			filenames:         []string{"b/test.go"},
 			currentlyApproved: sets.NewString(),
-			expectedFiles:     []File{UnapprovedFile{"b", "org", "project"}},
+			expectedFiles:     []File{UnapprovedFile{"b", "", "", "org", "project", "", nil}},
 		},
 		{
 			testName:          "Single File PR in B Fully Approved",
 			filenames:         []string{"b/test.go"},
 			currentlyApproved: bApprovers,
-			expectedFiles:     []File{ApprovedFile{"b", bApprovers, "org", "project"}},
+			expectedFiles:     []File{ApprovedFile{"b", bApprovers, "", "", "org", "project", ""}},
 		},
 		{
 			testName:          "Single Root File PR No One Approved",
 			filenames:         []string{"kubernetes.go"},
 			currentlyApproved: sets.NewString(),
-			expectedFiles:     []File{UnapprovedFile{"", "org", "project"}},
+			expectedFiles:     []File{UnapprovedFile{"", "", "", "org", "project", "", nil}},
 		},
 		{
 			testName:          "Combo and Other; Neither Approved",
 			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
 			currentlyApproved: sets.NewString(),
 			expectedFiles: []File{
-				UnapprovedFile{"a/combo", "org", "project"},
-				UnapprovedFile{"a/d", "org", "project"},
+				UnapprovedFile{"a/combo", "", "", "org", "project", "", nil},
+				UnapprovedFile{"a/d", "", "", "org", "project", "", nil},
 			},
 		},
 		{
@@ -179,8 +185,8 @@ func TestGetFiles(t *testing.T) {
 			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
 			currentlyApproved: eApprovers,
 			expectedFiles: []File{
-				ApprovedFile{"a/combo", eApprovers, "org", "project"},
-				UnapprovedFile{"a/d", "org", "project"},
+				ApprovedFile{"a/combo", eApprovers, "", "", "org", "project", ""},
+				UnapprovedFile{"a/d", "", "", "org", "project", "", nil},
 			},
 		},
 		{
@@ -188,8 +194,8 @@ func TestGetFiles(t *testing.T) {
 			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
 			currentlyApproved: edcApprovers.Intersection(dApprovers),
 			expectedFiles: []File{
-				ApprovedFile{"a/combo", edcApprovers.Intersection(dApprovers), "org", "project"},
-				ApprovedFile{"a/d", edcApprovers.Intersection(dApprovers), "org", "project"},
+				ApprovedFile{"a/combo", edcApprovers.Intersection(dApprovers), "", "", "org", "project", ""},
+				ApprovedFile{"a/d", edcApprovers.Intersection(dApprovers), "", "", "org", "project", ""},
 			},
 		},
 		{
@@ -197,9 +203,9 @@ func TestGetFiles(t *testing.T) {
 			filenames:         []string{"a/combo/test.go", "a/d/test.go", "c/test"},
 			currentlyApproved: cApprovers,
 			expectedFiles: []File{
-				ApprovedFile{"a/combo", cApprovers, "org", "project"},
-				UnapprovedFile{"a/d", "org", "project"},
-				ApprovedFile{"c", cApprovers, "org", "project"},
+				ApprovedFile{"a/combo", cApprovers, "", "", "org", "project", ""},
+				UnapprovedFile{"a/d", "", "", "org", "project", "", nil},
+				ApprovedFile{"c", cApprovers, "", "", "org", "project", ""},
 			},
 		},
 		{
@@ -207,8 +213,8 @@ func TestGetFiles(t *testing.T) {
 			filenames:         []string{"a/test.go", "a/d/test.go", "b/test"},
 			currentlyApproved: rootApprovers.Union(aApprovers).Union(bApprovers),
 			expectedFiles: []File{
-				ApprovedFile{"a", rootApprovers.Union(aApprovers), "org", "project"},
-				ApprovedFile{"b", rootApprovers.Union(bApprovers), "org", "project"},
+				ApprovedFile{"a", rootApprovers.Union(aApprovers), "", "", "org", "project", ""},
+				ApprovedFile{"b", rootApprovers.Union(bApprovers), "", "", "org", "project", ""},
 			},
 		},
 	}
@@ -216,15 +222,560 @@ func TestGetFiles(t *testing.T) {
 	for _, test := range tests {
 		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
 		for approver := range test.currentlyApproved {
-			testApprovers.AddApprover(approver, "REFERENCE")
+			testApprovers.AddApprover(approver, "REFERENCE", "")
 		}
-		calculated := testApprovers.GetFiles("org", "project")
+		calculated := testApprovers.GetFiles("org", "project", "")
 		if !reflect.DeepEqual(test.expectedFiles, calculated) {
 			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFiles, calculated)
 		}
 	}
 }
 
+func TestGetFilesBranch(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.AddApprover("Bill", "REFERENCE", "")
+
+	tests := []struct {
+		testName      string
+		branch        string
+		expectedFiles []string
+	}{
+		{
+			testName: "No Branch Defaults to Master",
+			branch:   "",
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+		},
+		{
+			testName: "Release Branch",
+			branch:   "release-1.6",
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/release-1.6/a/OWNERS)**\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/release-1.6/b/OWNERS)~~ [Bill]\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		files := ap.GetFiles("org", "project", test.branch)
+		calculated := []string{}
+		for _, file := range files {
+			calculated = append(calculated, file.String())
+		}
+		if !reflect.DeepEqual(test.expectedFiles, calculated) {
+			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFiles, calculated)
+		}
+	}
+}
+
+func TestGetFilesBranchResolver(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+			}),
+		},
+	)
+	ap.BranchResolver = func(org, project string) string {
+		if org == "org" && project == "new-project" {
+			return "main"
+		}
+		return "master"
+	}
+
+	tests := []struct {
+		testName     string
+		project      string
+		branch       string
+		expectedFile string
+	}{
+		{
+			testName:     "Resolved to main",
+			project:      "new-project",
+			expectedFile: "- **[a/OWNERS](https://github.com/org/new-project/blob/main/a/OWNERS)**\n",
+		},
+		{
+			testName:     "Resolved to master",
+			project:      "project",
+			expectedFile: "- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**\n",
+		},
+		{
+			testName:     "Explicit branch",
+			project:      "new-project",
+			branch:       "release-1.6",
+			expectedFile: "- **[a/OWNERS](https://github.com/org/new-project/blob/release-1.6/a/OWNERS)**\n",
+		},
+	}
+
+	for _, test := range tests {
+		files := ap.GetFiles("org", test.project, test.branch)
+		if len(files) != 1 || files[0].String() != test.expectedFile {
+			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFile, files)
+		}
+		message := GetMessage(ap, "org", test.project, test.branch)
+		if message == nil || !strings.Contains(*message, test.expectedFile) {
+			t.Errorf("Failed for test %v.  Expected %v in the message. Found %v", test.testName, test.expectedFile, message)
+		}
+	}
+}
+
+// filteredRepo is a FakeRepo whose files are owned by filters of the
+// OWNERS file of their directory, by extension.
+type filteredRepo struct {
+	FakeRepo
+}
+
+func (r filteredRepo) FindApproverOwnersForPath(path string) string {
+	dir := r.FakeRepo.FindApproverOwnersForPath(path)
+	if strings.HasSuffix(path, ".go") {
+		return features.FilteredOwnersKey(dir, `\.go$`)
+	}
+	return features.FilteredOwnersKey(dir, `^BUILD$`)
+}
+
+func TestOwnersFilters(t *testing.T) {
+	goKey := features.FilteredOwnersKey("a", `\.go$`)
+	buildKey := features.FilteredOwnersKey("a", `^BUILD$`)
+	repo := filteredRepo{createFakeRepo(map[string]sets.String{
+		"a":      sets.NewString(),
+		goKey:    sets.NewString("Gopher"),
+		buildKey: sets.NewString("Builder"),
+	})}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "a/BUILD"}, repo: repo, seed: TEST_SEED})
+
+	expectedApprovers := map[string]sets.String{
+		goKey:    sets.NewString("Gopher"),
+		buildKey: sets.NewString("Builder"),
+	}
+	if calculated := ap.owners.GetApprovers(); !reflect.DeepEqual(expectedApprovers, calculated) {
+		t.Errorf("Expected approvers: %v. Found %v", expectedApprovers, calculated)
+	}
+
+	ap.AddApprover("Builder", "REFERENCE", "")
+	if expected, calculated := sets.NewString(goKey), ap.UnapprovedFiles(); !expected.Equal(calculated) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, calculated)
+	}
+	if ap.IsFileApproved("a/test.go") || !ap.IsFileApproved("a/BUILD") {
+		t.Errorf("Expected only a/BUILD to be approved")
+	}
+
+	expectedFiles := []string{
+		"- **[a/OWNERS `\\.go$`](https://github.com/org/project/blob/master/a/OWNERS)**\n",
+		"- ~~[a/OWNERS `^BUILD$`](https://github.com/org/project/blob/master/a/OWNERS)~~ [Builder]\n",
+	}
+	calculated := []string{}
+	for _, file := range ap.GetFiles("org", "project", "") {
+		calculated = append(calculated, file.String())
+	}
+	if !reflect.DeepEqual(expectedFiles, calculated) {
+		t.Errorf("Expected files: %v. Found %v", expectedFiles, calculated)
+	}
+}
+
+// yamlScopedRepo is a FakeRepo where the OWNERS file of each directory
+// has a filter for its .yaml files.
+type yamlScopedRepo struct {
+	FakeRepo
+}
+
+func (r yamlScopedRepo) FindApproverOwnersForPath(path string) string {
+	dir := r.FakeRepo.FindApproverOwnersForPath(path)
+	if strings.HasSuffix(path, ".yaml") {
+		return features.FilteredOwnersKey(dir, `\.yaml$`)
+	}
+	return dir
+}
+
+func TestExtensionScopedApprovers(t *testing.T) {
+	yamlKey := features.FilteredOwnersKey("a", `\.yaml$`)
+	repo := yamlScopedRepo{createFakeRepo(map[string]sets.String{
+		"a":     sets.NewString("Art"),
+		yamlKey: sets.NewString("Yamler"),
+	})}
+	repo.ApproversMap[yamlKey] = sets.NewString("Yamler", "Art")
+
+	tests := []struct {
+		testName           string
+		filenames          []string
+		approver           string
+		expectedApproved   sets.String
+		expectedUnapproved sets.String
+	}{
+		{
+			testName:           "Scoped Approver on a Go File",
+			filenames:          []string{"a/main.go", "a/config.yaml"},
+			approver:           "Yamler",
+			expectedApproved:   sets.NewString("a/config.yaml"),
+			expectedUnapproved: sets.NewString("a"),
+		},
+		{
+			testName:           "Scoped Approver Alone",
+			filenames:          []string{"a/config.yaml"},
+			approver:           "Yamler",
+			expectedApproved:   sets.NewString("a/config.yaml"),
+			expectedUnapproved: sets.NewString(),
+		},
+		{
+			testName:           "Directory Approver",
+			filenames:          []string{"a/main.go", "a/config.yaml"},
+			approver:           "Art",
+			expectedApproved:   sets.NewString("a/main.go", "a/config.yaml"),
+			expectedUnapproved: sets.NewString(),
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(Owners{filenames: test.filenames, repo: repo, seed: TEST_SEED})
+		ap.AddApprover(test.approver, "REFERENCE", "")
+		for _, fn := range test.filenames {
+			if approved := ap.IsFileApproved(fn); approved != test.expectedApproved.Has(fn) {
+				t.Errorf("Failed for test %v.  Expected %v to be approved: %v. Found %v", test.testName, fn, test.expectedApproved.Has(fn), approved)
+			}
+		}
+		if calculated := ap.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, calculated)
+		}
+	}
+}
+
+func TestGetFilesEligibleApprovers(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice", "Art"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.ListEligibleApprovers = true
+	ap.AddApprover("Bill", "REFERENCE", "")
+
+	expectedFiles := []string{
+		"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** needs approval from one of [Alice,Art]\n",
+		"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+	}
+	calculated := []string{}
+	for _, file := range ap.GetFiles("org", "project", "") {
+		calculated = append(calculated, file.String())
+	}
+	if !reflect.DeepEqual(expectedFiles, calculated) {
+		t.Errorf("Expected files: %v. Found %v", expectedFiles, calculated)
+	}
+}
+
+func TestGetFilesUnapprovable(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString(),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+
+	expectedFiles := []string{
+		"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** :warning: lists no approvers, a human must intervene\n",
+		"- **[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)**\n",
+	}
+	calculated := []string{}
+	for _, file := range ap.GetFiles("org", "project", "") {
+		calculated = append(calculated, file.String())
+	}
+	if !reflect.DeepEqual(expectedFiles, calculated) {
+		t.Errorf("Expected files: %v. Found %v", expectedFiles, calculated)
+	}
+	if expected, found := sets.NewString("a"), ap.UnapprovableFiles(); !expected.Equal(found) {
+		t.Errorf("Expected unapprovable files: %v. Found %v", expected, found)
+	}
+	if expected, found := sets.NewString("a", "b"), ap.UnapprovedFiles(); !expected.Equal(found) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, found)
+	}
+}
+
+func TestGetFilesBaseURL(t *testing.T) {
+	tests := []struct {
+		testName     string
+		baseURL      string
+		expectedLink string
+	}{
+		{
+			testName:     "Default github.com",
+			baseURL:      "",
+			expectedLink: "https://github.com/org/project/blob/master/a/OWNERS",
+		},
+		{
+			testName:     "GitHub Enterprise",
+			baseURL:      "https://github.example.com",
+			expectedLink: "https://github.example.com/org/project/blob/master/a/OWNERS",
+		},
+		{
+			testName:     "GitHub Enterprise With Trailing Slash",
+			baseURL:      "https://github.example.com/",
+			expectedLink: "https://github.example.com/org/project/blob/master/a/OWNERS",
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(
+			Owners{
+				filenames: []string{"a/a.go"},
+				repo:      createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}),
+			},
+		)
+		ap.BaseURL = test.baseURL
+		ap.AddApprover("Alice", "REFERENCE", "")
+		expected := "- ~~[a/OWNERS](" + test.expectedLink + ")~~ [Alice]\n"
+		if calculated := ap.GetFiles("org", "project", "")[0].String(); calculated != expected {
+			t.Errorf("Failed for test %v.  Expected file: %v. Found %v", test.testName, expected, calculated)
+		}
+	}
+}
+
+func TestGetFilesOwnersFileName(t *testing.T) {
+	tests := []struct {
+		testName       string
+		ownersFileName string
+		expectedFiles  []string
+	}{
+		{
+			testName:       "Default OWNERS",
+			ownersFileName: "",
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+		},
+		{
+			testName:       "Custom File Name",
+			ownersFileName: "MAINTAINERS",
+			expectedFiles: []string{
+				"- **[a/MAINTAINERS](https://github.com/org/project/blob/master/a/MAINTAINERS)**\n",
+				"- ~~[b/MAINTAINERS](https://github.com/org/project/blob/master/b/MAINTAINERS)~~ [Bill]\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(
+			Owners{
+				filenames: []string{"a/a.go", "b/b.go"},
+				repo: createFakeRepo(map[string]sets.String{
+					"a": sets.NewString("Alice"),
+					"b": sets.NewString("Bill"),
+				}),
+			},
+		)
+		ap.OwnersFileName = test.ownersFileName
+		ap.AddApprover("Bill", "REFERENCE", "")
+		calculated := []string{}
+		for _, file := range ap.GetFiles("org", "project", "") {
+			calculated = append(calculated, file.String())
+		}
+		if !reflect.DeepEqual(test.expectedFiles, calculated) {
+			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFiles, calculated)
+		}
+	}
+}
+
+// namingRepo is a FakeRepo whose ownership files have the given names.
+type namingRepo struct {
+	FakeRepo
+	names map[string]string
+}
+
+func (r namingRepo) OwnersFileName(path string) string {
+	return r.names[path]
+}
+
+func TestGetFilesMissingLabels(t *testing.T) {
+	repo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Alice"),
+		"b": sets.NewString("Bill"),
+	})
+	repo.RequiredLabelsMap = map[string]sets.String{"a": sets.NewString("sig/a", "area/a")}
+	// Labels applied automatically aren't required.
+	repo.LabelsMap = map[string]sets.String{"b": sets.NewString("sig/b")}
+	unlabeled := "- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**\n"
+	tests := []struct {
+		testName        string
+		labels          []string
+		expectedMissing sets.String
+		expectedFileA   string
+	}{
+		{
+			testName:        "Labels Unknown",
+			expectedMissing: sets.NewString("area/a", "sig/a"),
+			expectedFileA:   unlabeled,
+		},
+		{
+			testName:        "Labels Present",
+			labels:          []string{"sig/a", "area/a", "lgtm"},
+			expectedMissing: sets.NewString(),
+			expectedFileA:   unlabeled,
+		},
+		{
+			testName:        "Label Absent",
+			labels:          []string{"sig/a"},
+			expectedMissing: sets.NewString("area/a"),
+			expectedFileA:   unlabeled + "  - :warning: requires the label `area/a`\n",
+		},
+		{
+			testName:        "No Labels",
+			labels:          []string{},
+			expectedMissing: sets.NewString("area/a", "sig/a"),
+			expectedFileA:   unlabeled + "  - :warning: requires the labels `area/a`, `sig/a`\n",
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(Owners{filenames: []string{"a/a.go", "b/b.go"}, repo: repo, seed: TEST_SEED})
+		if test.labels != nil {
+			ap.AddLabels(test.labels...)
+		}
+		if found := ap.MissingRequiredLabels(sets.NewString(test.labels...)); !test.expectedMissing.Equal(found) {
+			t.Errorf("Failed for test %v.  Expected missing labels: %v. Found %v", test.testName, test.expectedMissing, found)
+		}
+		files := ap.GetFiles("org", "project", "")
+		if found := files[0].String(); found != test.expectedFileA {
+			t.Errorf("Failed for test %v.  Expected file: %q. Found %q", test.testName, test.expectedFileA, found)
+		}
+		if found := files[1].String(); strings.Contains(found, "label") {
+			t.Errorf("Failed for test %v.  Expected no label warning for b. Found %q", test.testName, found)
+		}
+	}
+}
+
+func TestGetFilesOwnersFileNamer(t *testing.T) {
+	repo := namingRepo{
+		FakeRepo: createFakeRepo(map[string]sets.String{
+			"a": sets.NewString("Alice"),
+			"b": sets.NewString("Bill"),
+		}),
+		names: map[string]string{"a": "MAINTAINERS", "b": "OWNERS"},
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/a.go", "b/b.go"}, repo: NewRepoAlias(repo, *features.NewAliases(map[string][]string{}))})
+	ap.AddApprover("Bill", "REFERENCE", "")
+
+	expectedFiles := []string{
+		"- **[a/MAINTAINERS](https://github.com/org/project/blob/master/a/MAINTAINERS)**\n",
+		"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+	}
+	calculated := []string{}
+	for _, file := range ap.GetFiles("org", "project", "") {
+		calculated = append(calculated, file.String())
+	}
+	if !reflect.DeepEqual(expectedFiles, calculated) {
+		t.Errorf("Expected files: %v. Found %v", expectedFiles, calculated)
+	}
+}
+
+// selectingRepo is a FakeRepo whose directory "a" also has a legacy
+// MAINTAINERS file, read when MAINTAINERS takes precedence.
+type selectingRepo struct {
+	FakeRepo
+}
+
+func (r selectingRepo) WithOwnersFileNames(names []string) RepoInterface {
+	if names[0] != "MAINTAINERS" {
+		return r
+	}
+	return namingRepo{
+		FakeRepo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Legacy")}),
+		names:    map[string]string{"a": "MAINTAINERS"},
+	}
+}
+
+func TestNewOwnersOwnersFileNames(t *testing.T) {
+	repo := NewRepoAlias(selectingRepo{createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")})}, *features.NewAliases(map[string][]string{}))
+
+	tests := []struct {
+		testName          string
+		ownersFileNames   []string
+		expectedApprovers sets.String
+		expectedFiles     []string
+	}{
+		{
+			testName:          "Default Names",
+			expectedApprovers: sets.NewString("Alice"),
+			expectedFiles:     []string{"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**\n"},
+		},
+		{
+			testName:          "Legacy File First",
+			ownersFileNames:   []string{"MAINTAINERS", "OWNERS"},
+			expectedApprovers: sets.NewString("Legacy"),
+			expectedFiles:     []string{"- **[a/MAINTAINERS](https://github.com/org/project/blob/master/a/MAINTAINERS)**\n"},
+		},
+	}
+	for _, test := range tests {
+		owners := NewOwners([]string{"a/a.go"}, repo, TEST_SEED, test.ownersFileNames...)
+		if calculated := owners.GetApprovers()["a"]; !test.expectedApprovers.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected approvers: %v. Found %v", test.testName, test.expectedApprovers, calculated)
+		}
+		calculated := []string{}
+		for _, file := range NewApprovers(owners).GetFiles("org", "project", "") {
+			calculated = append(calculated, file.String())
+		}
+		if !reflect.DeepEqual(test.expectedFiles, calculated) {
+			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFiles, calculated)
+		}
+	}
+}
+
+func TestApprovedFileCollapsesApprovers(t *testing.T) {
+	tests := []struct {
+		testName  string
+		approvers sets.String
+		expected  string
+	}{
+		{
+			testName:  "Fewer Than the Maximum",
+			approvers: sets.NewString("Alice", "Bob"),
+			expected:  "[Alice,Bob]",
+		},
+		{
+			testName:  "Exactly the Maximum",
+			approvers: sets.NewString("Alice", "Bob", "Carol"),
+			expected:  "[Alice,Bob,Carol]",
+		},
+		{
+			testName:  "One More Than the Maximum",
+			approvers: sets.NewString("Alice", "Bob", "Carol", "Dave"),
+			expected:  "[Alice,Bob,Carol and 1 other]",
+		},
+		{
+			testName:  "More Than the Maximum",
+			approvers: sets.NewString("Alice", "Bob", "Carol", "Dave", "Eve"),
+			expected:  "[Alice,Bob,Carol and 2 others]",
+		},
+	}
+
+	defer func(max int) { MaxListedApprovers = max }(MaxListedApprovers)
+	MaxListedApprovers = 3
+	for _, test := range tests {
+		file := ApprovedFile{"a", test.approvers, "", "", "org", "project", ""}
+		expected := "- ~~[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)~~ " + test.expected + "\n"
+		if calculated := file.String(); calculated != expected {
+			t.Errorf("Failed for test %v.  Expected: %v. Found %v", test.testName, expected, calculated)
+		}
+		if file.approvers.Len() != test.approvers.Len() {
+			t.Errorf("Failed for test %v.  Expected the approvers to be kept, found %v", test.testName, file.approvers)
+		}
+	}
+}
+
 func TestGetCCs(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
 	aApprovers := sets.NewString("Art", "Anne")
@@ -284,14 +835,14 @@ func TestGetCCs(t *testing.T) {
 			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
 			testSeed:          0,
 			currentlyApproved: sets.NewString(),
-			expectedCCs:       []string{"Dan"},
+			expectedCCs:       []string{"Debbie"},
 		},
 		{
 			testName:          "Combo and Other; Combo Approved",
 			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
 			testSeed:          0,
 			currentlyApproved: eApprovers,
-			expectedCCs:       []string{"Dan"},
+			expectedCCs:       []string{"Debbie"},
 		},
 		{
 			testName:          "Combo and Other; Both Approved",
@@ -306,7 +857,7 @@ func TestGetCCs(t *testing.T) {
 			testSeed:          0,
 			currentlyApproved: sets.NewString(),
 			// chris can approve c and combo, debbie can approve d
-			expectedCCs: []string{"Chris", "Debbie"},
+			expectedCCs: []string{"Carol", "Debbie"},
 		},
 		{
 			testName:          "A, B, C; Nothing Approved",
@@ -360,7 +911,7 @@ func TestGetCCs(t *testing.T) {
 	for _, test := range tests {
 		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: test.testSeed})
 		for approver := range test.currentlyApproved {
-			testApprovers.AddApprover(approver, "REFERENCE")
+			testApprovers.AddApprover(approver, "REFERENCE", "")
 		}
 		testApprovers.AddAssignees(test.assignees...)
 		calculated := testApprovers.GetCCs()
@@ -370,283 +921,3023 @@ func TestGetCCs(t *testing.T) {
 	}
 }
 
-func TestIsApproved(t *testing.T) {
-	rootApprovers := sets.NewString("Alice", "Bob")
-	aApprovers := sets.NewString("Art", "Anne")
-	bApprovers := sets.NewString("Bill", "Ben", "Barbara")
-	cApprovers := sets.NewString("Chris", "Carol")
-	dApprovers := sets.NewString("David", "Dan", "Debbie")
-	eApprovers := sets.NewString("Eve", "Erin")
-	edcApprovers := eApprovers.Union(dApprovers).Union(cApprovers)
+func TestGetCCsLoadFunc(t *testing.T) {
 	FakeRepoMap := map[string]sets.String{
-		"":        rootApprovers,
-		"a":       aApprovers,
-		"b":       bApprovers,
-		"c":       cApprovers,
-		"a/d":     dApprovers,
-		"a/combo": edcApprovers,
+		"":  sets.NewString("Alice", "Bob"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Anne", "Bill"),
 	}
+	load := map[string]int{"Alice": 5, "Art": 1, "Anne": 30}
+
 	tests := []struct {
-		testName          string
-		filenames         []string
-		currentlyApproved sets.String
-		testSeed          int64
-		isApproved        bool
+		testName    string
+		filenames   []string
+		loadFunc    func(string) int
+		expectedCCs []string
 	}{
 		{
-			testName:          "Empty PR",
-			filenames:         []string{},
+			testName:    "Lowest Load Wins a Tie",
+			filenames:   []string{"kubernetes.go"},
+			loadFunc:    func(login string) int { return load[login] },
+			expectedCCs: []string{"Bob"},
+		},
+		{
+			testName:    "Busy Approver Covering More Files Is Penalized",
+			filenames:   []string{"a/test.go", "b/test.go"},
+			loadFunc:    func(login string) int { return load[login] },
+			expectedCCs: []string{"Art", "Bill"},
+		},
+		{
+			testName:    "No Load Function",
+			filenames:   []string{"a/test.go", "b/test.go"},
+			loadFunc:    nil,
+			expectedCCs: []string{"Anne"},
+		},
+	}
+
+	for _, test := range tests {
+		// The load must decide, whatever the seed is.
+		for seed := int64(0); seed < 10; seed++ {
+			testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			testApprovers.LoadFunc = test.loadFunc
+			calculated := testApprovers.GetCCs()
+			if !reflect.DeepEqual(test.expectedCCs, calculated) {
+				t.Errorf("Failed for test %v with seed %v.  Expected CCs: %v. Found %v", test.testName, seed, test.expectedCCs, calculated)
+			}
+		}
+	}
+}
+
+func TestApprovedByRoot(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":      sets.NewString("Alice"),
+		"a":     sets.NewString("Art"),
+		"b":     sets.NewString("Bill"),
+		"b/c/d": sets.NewString("Dan"),
+	}
+	filenames := []string{"a/test.go", "b/test.go", "b/c/d/test.go"}
+	tests := []struct {
+		testName           string
+		noParentOwners     sets.String
+		requiredApprovals  map[string]int
+		approve            func(ap *Approvers)
+		expectedRoot       bool
+		expectedUnapproved sets.String
+	}{
+		{
+			testName:           "Root Approver Covers Everything",
+			noParentOwners:     sets.NewString(),
+			approve:            func(ap *Approvers) { ap.AddApprover("alice", "REFERENCE", "") },
+			expectedRoot:       true,
+			expectedUnapproved: sets.NewString(),
+		},
+		{
+			testName:           "No Parent Owners Subtree Still Blocks",
+			noParentOwners:     sets.NewString("b"),
+			approve:            func(ap *Approvers) { ap.AddApprover("Alice", "REFERENCE", "") },
+			expectedUnapproved: sets.NewString("b"),
+		},
+		{
+			testName:           "Several Approvals Required",
+			noParentOwners:     sets.NewString(),
+			requiredApprovals:  map[string]int{"a": 2},
+			approve:            func(ap *Approvers) { ap.AddApprover("Alice", "REFERENCE", "") },
+			expectedUnapproved: sets.NewString("a"),
+		},
+		{
+			testName:           "Root Approval Scoped To A File",
+			noParentOwners:     sets.NewString(),
+			approve:            func(ap *Approvers) { ap.AddApproverForFile("Alice", "a/test.go", "REFERENCE") },
+			expectedUnapproved: sets.NewString("b"),
+		},
+		{
+			testName:           "Leaf Approver",
+			noParentOwners:     sets.NewString(),
+			approve:            func(ap *Approvers) { ap.AddApprover("Bill", "REFERENCE", "") },
+			expectedUnapproved: sets.NewString("a"),
+		},
+	}
+
+	for _, test := range tests {
+		repo := createFakeRepoNoParentOwners(FakeRepoMap, test.noParentOwners)
+		repo.RequiredApprovalsMap = test.requiredApprovals
+		ap := NewApprovers(NewOwners(filenames, repo, TEST_SEED))
+		test.approve(&ap)
+		if found := ap.approvedByRoot(); found != test.expectedRoot {
+			t.Errorf("Failed for test %v.  Expected approved by root: %v. Found %v", test.testName, test.expectedRoot, found)
+		}
+		if found := ap.UnapprovedFiles(); !test.expectedUnapproved.Equal(found) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, found)
+		}
+		if found := ap.IsApproved(); found != (test.expectedUnapproved.Len() == 0) {
+			t.Errorf("Failed for test %v.  Expected approved: %v. Found %v", test.testName, test.expectedUnapproved.Len() == 0, found)
+		}
+	}
+}
+
+func TestSuggestExcluding(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Bill"),
+	}
+	tests := []struct {
+		testName    string
+		exclude     sets.String
+		approvers   []string
+		expectedCCs []string
+	}{
+		{
+			testName:    "Covering Approver Excluded",
+			exclude:     sets.NewString("Art"),
+			approvers:   []string{"Bill"},
+			expectedCCs: []string{"Anne"},
+		},
+		{
+			testName:    "Excluded Ignoring Case",
+			exclude:     sets.NewString("art", "anne"),
+			approvers:   []string{"Bill"},
+			expectedCCs: []string{"Alice"},
+		},
+		{
+			testName:    "Only Leaf Approver Excluded",
+			exclude:     sets.NewString("Bill"),
+			approvers:   []string{"Art"},
+			expectedCCs: []string{"Alice"},
+		},
+	}
+
+	for _, test := range tests {
+		for seed := int64(0); seed < 5; seed++ {
+			ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			ap.AddAssignees(test.exclude.List()...)
+			for _, approver := range test.approvers {
+				ap.AddApprover(approver, "REFERENCE", "")
+			}
+			if calculated := ap.SuggestExcluding(test.exclude); !reflect.DeepEqual(calculated, test.expectedCCs) {
+				t.Errorf("Failed for test %v.  Expected suggestions: %v. Found %v", test.testName, test.expectedCCs, calculated)
+			}
+		}
+	}
+}
+
+func TestMinDistinctApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice", "Bob"),
+		"a": sets.NewString("Art", "Amy"),
+	}
+	tests := []struct {
+		testName         string
+		minimum          int
+		approvers        []string
+		expectedMinimum  bool
+		expectedApproved bool
+	}{
+		{
+			testName:         "No Minimum",
+			approvers:        []string{"Alice"},
+			expectedMinimum:  true,
+			expectedApproved: true,
+		},
+		{
+			testName:         "Covered By One Person Of Two",
+			minimum:          2,
+			approvers:        []string{"Alice"},
+			expectedMinimum:  false,
+			expectedApproved: false,
+		},
+		{
+			testName:         "Same Person Twice",
+			minimum:          2,
+			approvers:        []string{"Alice", "alice"},
+			expectedMinimum:  false,
+			expectedApproved: false,
+		},
+		{
+			testName:         "Two People",
+			minimum:          2,
+			approvers:        []string{"Alice", "Art"},
+			expectedMinimum:  true,
+			expectedApproved: true,
+		},
+		{
+			testName:         "Two People Without Coverage",
+			minimum:          2,
+			approvers:        []string{"Art", "Amy"},
+			expectedMinimum:  true,
+			expectedApproved: false,
+		},
+		{
+			testName:         "Second Person Owning Nothing",
+			minimum:          2,
+			approvers:        []string{"Alice", "Carol"},
+			expectedMinimum:  false,
+			expectedApproved: false,
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		ap.MinDistinctApprovers = test.minimum
+		for _, approver := range test.approvers {
+			ap.AddApprover(approver, "REFERENCE", "")
+		}
+		if calculated := ap.MeetsDistinctApproverMinimum(test.minimum); calculated != test.expectedMinimum {
+			t.Errorf("Failed for test %v.  Expected minimum met: %v. Found %v", test.testName, test.expectedMinimum, calculated)
+		}
+		if calculated := ap.IsApproved(); calculated != test.expectedApproved {
+			t.Errorf("Failed for test %v.  Expected approved: %v. Found %v", test.testName, test.expectedApproved, calculated)
+		}
+	}
+
+	// Every file is approved, but by a single approver.
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.MinDistinctApprovers = 2
+	ap.AddApprover("Alice", "REFERENCE", "")
+	for _, message := range []*string{GetMessage(ap, "org", "project", ""), GetPlainTextMessage(ap, "org", "project")} {
+		if message == nil {
+			t.Fatalf("Failed to render the message")
+		}
+		if !strings.Contains(*message, "This PR needs the approval of 1 more approver from the OWNERS files, to be approved by 2 different approvers.") {
+			t.Errorf("Expected the message to explain the missing approver. Found %v", *message)
+		}
+		for _, section := range []string{"We suggest", "/assign "} {
+			if strings.Contains(*message, section) {
+				t.Errorf("Expected no %q without suggestions. Found %v", section, *message)
+			}
+		}
+	}
+}
+
+func TestPinnedSuggestions(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Art", "Anne", "Amy"),
+		"b": sets.NewString("Bill", "Ben", "Barbara"),
+	}
+	tests := []struct {
+		testName    string
+		pinned      []string
+		approvers   []string
+		excluded    sets.String
+		expectedCCs []string
+		// expectedReplacements are the people among whom one more
+		// approver is suggested, if any.
+		expectedReplacements sets.String
+	}{
+		{
+			testName:    "Pinned Suggestions Still Needed",
+			pinned:      []string{"Anne", "Ben"},
+			expectedCCs: []string{"Anne", "Ben"},
+		},
+		{
+			testName:    "Pinned Suggestion No Longer Needed",
+			pinned:      []string{"Anne", "Ben"},
+			approvers:   []string{"Bill"},
+			expectedCCs: []string{"Anne"},
+		},
+		{
+			testName:             "Pinned Suggestion Not An Approver",
+			pinned:               []string{"Anne", "Zed"},
+			expectedCCs:          []string{"Anne"},
+			expectedReplacements: sets.NewString("Bill", "Ben", "Barbara"),
+		},
+		{
+			testName:             "Pinned Suggestion Excluded",
+			pinned:               []string{"Anne", "Ben"},
+			excluded:             sets.NewString("Ben"),
+			expectedCCs:          []string{"Anne"},
+			expectedReplacements: sets.NewString("Bill", "Barbara"),
+		},
+	}
+
+	for _, test := range tests {
+		for seed := int64(0); seed < 5; seed++ {
+			ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			ap.ExcludedApprovers = test.excluded
+			for _, approver := range test.approvers {
+				ap.AddApprover(approver, "REFERENCE", "")
+			}
+			ap.SetPinnedSuggestions(test.pinned)
+			calculated := sets.NewString(ap.GetCCs()...)
+			expected := sets.NewString(test.expectedCCs...)
+			if test.expectedReplacements != nil {
+				replacements := calculated.Difference(expected)
+				if replacements.Len() != 1 || !test.expectedReplacements.IsSuperset(replacements) {
+					t.Errorf("Failed for test %v.  Expected one replacement among %v. Found %v", test.testName, test.expectedReplacements, replacements)
+				}
+				calculated = calculated.Difference(replacements)
+			}
+			if !expected.Equal(calculated) {
+				t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, expected, calculated)
+			}
+		}
+	}
+}
+
+func TestGetCCsRootCoveragePenalty(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	tests := []struct {
+		testName    string
+		assignees   []string
+		penalty     float64
+		expectedCCs []string
+	}{
+		{
+			testName:    "Root Assignee Without Penalty",
+			assignees:   []string{"Alice", "Art", "Bill"},
+			expectedCCs: []string{"Alice"},
+		},
+		{
+			testName:    "Leaf Assignees With Penalty",
+			assignees:   []string{"Alice", "Art", "Bill"},
+			penalty:     0.6,
+			expectedCCs: []string{"Art", "Bill"},
+		},
+		{
+			testName:    "Root Assignee Needed With Penalty",
+			assignees:   []string{"Alice", "Art"},
+			penalty:     0.6,
+			expectedCCs: []string{"Alice", "Art"},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.RootCoveragePenalty = test.penalty
+		testApprovers.AddAssignees(test.assignees...)
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, test.expectedCCs) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestGetCCsCooldown(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Anne", "Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	tests := []struct {
+		testName    string
+		filenames   []string
+		cooling     sets.String
+		expectedCCs []string
+	}{
+		{
+			testName:    "No Cooldown",
+			filenames:   []string{"a/test.go", "b/test.go"},
+			cooling:     sets.NewString(),
+			expectedCCs: []string{"Anne"},
+		},
+		{
+			testName:    "Covering Approver Cooling Down",
+			filenames:   []string{"a/test.go", "b/test.go"},
+			cooling:     sets.NewString("Anne"),
+			expectedCCs: []string{"Art", "Bill"},
+		},
+		{
+			testName:    "Cooling Approver Uniquely Needed",
+			filenames:   []string{"a/test.go", "c/test.go"},
+			cooling:     sets.NewString("Carl", "Art"),
+			expectedCCs: []string{"Anne", "Carl"},
+		},
+	}
+
+	for _, test := range tests {
+		for seed := int64(0); seed < 5; seed++ {
+			testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			testApprovers.CooldownFunc = test.cooling.Has
+			if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, test.expectedCCs) {
+				t.Errorf("Failed for test %v with seed %d.  Expected CCs: %v. Found %v", test.testName, seed, test.expectedCCs, calculated)
+			}
+		}
+	}
+}
+
+func TestGetCCsTieBreak(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice", "Bob"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Anne", "Bill"),
+	}
+
+	tests := []struct {
+		testName    string
+		filenames   []string
+		tieBreak    func(a, b string) bool
+		expectedCCs []string
+	}{
+		{
+			testName:    "Alphabetical",
+			filenames:   []string{"kubernetes.go"},
+			tieBreak:    func(a, b string) bool { return a < b },
+			expectedCCs: []string{"Alice"},
+		},
+		{
+			testName:    "Reverse Alphabetical",
+			filenames:   []string{"kubernetes.go"},
+			tieBreak:    func(a, b string) bool { return a > b },
+			expectedCCs: []string{"Bob"},
+		},
+		{
+			testName:    "No Tie",
+			filenames:   []string{"a/test.go", "b/test.go"},
+			tieBreak:    func(a, b string) bool { return a > b },
+			expectedCCs: []string{"Anne"},
+		},
+	}
+
+	for _, test := range tests {
+		// The tie break must decide, whatever the seed is.
+		for seed := int64(0); seed < 10; seed++ {
+			testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			testApprovers.TieBreak = test.tieBreak
+			calculated := testApprovers.GetCCs()
+			if !reflect.DeepEqual(test.expectedCCs, calculated) {
+				t.Errorf("Failed for test %v with seed %v.  Expected CCs: %v. Found %v", test.testName, seed, test.expectedCCs, calculated)
+			}
+		}
+	}
+}
+
+func TestGetRedundantAssignees(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice", "Bob"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Bill", "Ben"),
+		"c": sets.NewString("Chris", "Carol"),
+	}
+	tests := []struct {
+		testName          string
+		filenames         []string
+		currentlyApproved sets.String
+		assignees         []string
+		expectedRedundant sets.String
+	}{
+		{
+			testName:          "No Assignees",
+			filenames:         []string{"a/test.go", "b/test.go"},
 			currentlyApproved: sets.NewString(),
-			testSeed:          0,
-			isApproved:        true,
+			assignees:         []string{},
+			expectedRedundant: sets.NewString(),
 		},
 		{
-			testName:          "Single Root File PR Approved",
-			filenames:         []string{"kubernetes.go"},
-			currentlyApproved: sets.NewString(rootApprovers.List()[0]),
-			testSeed:          3,
-			isApproved:        true,
+			testName:          "Assignee Covers a Unique File",
+			filenames:         []string{"a/test.go", "b/test.go"},
+			currentlyApproved: sets.NewString(),
+			assignees:         []string{"Art"},
+			expectedRedundant: sets.NewString(),
+		},
+		{
+			testName:          "Assignee Covers Nothing",
+			filenames:         []string{"a/test.go", "b/test.go"},
+			currentlyApproved: sets.NewString(),
+			assignees:         []string{"Art", "Chris"},
+			expectedRedundant: sets.NewString("Chris"),
+		},
+		{
+			testName:          "Assignee Covers an Approved File",
+			filenames:         []string{"a/test.go", "b/test.go"},
+			currentlyApproved: sets.NewString("Anne"),
+			assignees:         []string{"Art", "Bill"},
+			expectedRedundant: sets.NewString("Art"),
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		for approver := range test.currentlyApproved {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		testApprovers.AddAssignees(test.assignees...)
+		calculated := testApprovers.GetRedundantAssignees()
+		if !test.expectedRedundant.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected redundant assignees: %v. Found %v", test.testName, test.expectedRedundant, calculated)
+		}
+	}
+}
+
+func TestAddApprovalNeverDowngrades(t *testing.T) {
+	lgtm := func(ap *Approvers) { ap.AddLGTMer("Alice", "LGTM_REFERENCE", "") }
+	approve := func(ap *Approvers) { ap.AddApprover("Alice", "APPROVE_REFERENCE", "") }
+	selfApprove := func(ap *Approvers) { ap.AddAuthorSelfApprover("Alice", "SELF_REFERENCE") }
+	tests := []struct {
+		testName    string
+		adds        []func(ap *Approvers)
+		expectedHow How
+	}{
+		{testName: "LGTM then Approve", adds: []func(ap *Approvers){lgtm, approve}, expectedHow: HowApproved},
+		{testName: "Approve then LGTM", adds: []func(ap *Approvers){approve, lgtm}, expectedHow: HowApproved},
+		{testName: "LGTM then self-approval", adds: []func(ap *Approvers){lgtm, selfApprove}, expectedHow: HowAuthorSelfApproved},
+		{testName: "Self-approval then LGTM", adds: []func(ap *Approvers){selfApprove, lgtm}, expectedHow: HowAuthorSelfApproved},
+		{testName: "Self-approval then Approve", adds: []func(ap *Approvers){selfApprove, approve}, expectedHow: HowApproved},
+		{testName: "Approve then self-approval", adds: []func(ap *Approvers){approve, selfApprove}, expectedHow: HowApproved},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}), seed: TEST_SEED})
+		for _, add := range test.adds {
+			add(&ap)
+		}
+		approvals := ap.ListApprovals()
+		if len(approvals) != 1 || approvals[0].How != test.expectedHow {
+			t.Errorf("Failed for test %v.  Expected a single approval with How %q. Found %v", test.testName, test.expectedHow, approvals)
+		}
+	}
+}
+
+func TestSelfApprovals(t *testing.T) {
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice", "Bob")}), seed: TEST_SEED})
+	if approvals := ap.SelfApprovals(); len(approvals) != 0 {
+		t.Errorf("Expected no self approvals. Found %v", approvals)
+	}
+
+	ap.AddAuthorSelfApprover("Alice", "https://github.com/org/project/pull/1#issuecomment-1")
+	ap.AddApprover("Bob", "REFERENCE2", "")
+	ap.AddReviewApprover("Carl", "REFERENCE3")
+	expected := []Approval{{Login: "Alice", How: HowAuthorSelfApproved, Reference: "https://github.com/org/project/pull/1#issuecomment-1"}}
+	if approvals := ap.SelfApprovals(); !reflect.DeepEqual(approvals, expected) {
+		t.Errorf("Expected self approvals %v. Found %v", expected, approvals)
+	}
+	if rendered := expected[0].String(); rendered != `*<a href="https://github.com/org/project/pull/1#issuecomment-1" title="Author self-approved">Alice</a>*` {
+		t.Errorf("Unexpected rendering of the self approval: %v", rendered)
+	}
+}
+
+func TestAddApproverCaseInsensitive(t *testing.T) {
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}), seed: TEST_SEED})
+	ap.AddApprover("Alice", "REFERENCE", "")
+	ap.AddLGTMer("alice", "REFERENCE2", "")
+
+	expected := []Approval{{Login: "Alice", How: "Approved", Reference: "REFERENCE"}}
+	if approvals := ap.ListApprovals(); !reflect.DeepEqual(approvals, expected) {
+		t.Errorf("Expected approvals %v. Found %v", expected, approvals)
+	}
+	if !ap.IsApproved() {
+		t.Errorf("Expected PR to be approved")
+	}
+
+	ap.RemoveApprover("ALICE")
+	if approvers := ap.GetCurrentApproversSet(); approvers.Len() != 0 {
+		t.Errorf("Expected no approvers. Found %v", approvers)
+	}
+}
+
+func TestAddReviewApprover(t *testing.T) {
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}), seed: TEST_SEED})
+	ap.AddReviewApprover("Alice", "https://github.com/org/project/pull/1#pullrequestreview-42")
+
+	if !ap.IsApproved() {
+		t.Errorf("Expected the review approval to approve the PR")
+	}
+	expected := `*<a href="https://github.com/org/project/pull/1#pullrequestreview-42" title="Approved via review">Alice</a>*`
+	if approvals := ap.ListApprovals(); len(approvals) != 1 || approvals[0].String() != expected {
+		t.Errorf("Expected approval %v. Found %v", expected, approvals)
+	}
+}
+
+func TestApprovalString(t *testing.T) {
+	tests := []struct {
+		testName  string
+		reference string
+		expected  string
+	}{
+		{
+			testName:  "Comment Reference",
+			reference: CommentReference("org", "project", 12, 345),
+			expected:  `*<a href="https://github.com/org/project/pull/12#issuecomment-345" title="Approved">Alice</a>*`,
+		},
+		{
+			testName:  "Escaped URL",
+			reference: `https://example.com/"quoted"`,
+			expected:  `*<a href="https://example.com/&#34;quoted&#34;" title="Approved">Alice</a>*`,
+		},
+		{
+			testName:  "Not a URL",
+			reference: "REFERENCE",
+			expected:  `*Alice*`,
+		},
+		{
+			testName:  "Script URL",
+			reference: "javascript:alert(1)",
+			expected:  `*Alice*`,
+		},
+		{
+			testName:  "Empty Reference",
+			reference: "",
+			expected:  `*Alice*`,
+		},
+	}
+
+	for _, test := range tests {
+		approval := Approval{Login: "Alice", How: HowApproved, Reference: test.reference}
+		if rendered := approval.String(); rendered != test.expected {
+			t.Errorf("Failed for test %v.  Expected %v. Found %v", test.testName, test.expected, rendered)
+		}
+	}
+}
+
+func TestAddDelegatedApprover(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Alice"),
+		"b": sets.NewString("Bill"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddDelegatedApprover("Dave", "Alice", "https://github.com/org/project/pull/1#issuecomment-1")
+
+	if !ap.IsFileApproved("a/test.go") {
+		t.Errorf("Expected a to be approved through Alice's delegate")
+	}
+	if ap.IsFileApproved("b/test.go") {
+		t.Errorf("Expected b to remain unapproved")
+	}
+	if approvers := ap.GetCurrentApproversSet(); !approvers.Equal(sets.NewString("Alice")) {
+		t.Errorf("Expected the approval to be credited to Alice. Found %v", approvers)
+	}
+	expected := `*<a href="https://github.com/org/project/pull/1#issuecomment-1" title="Approved by delegate Dave">Alice</a>*`
+	if approvals := ap.ListApprovals(); len(approvals) != 1 || approvals[0].String() != expected {
+		t.Errorf("Expected approval %v. Found %v", expected, approvals)
+	}
+
+	ap.AddDelegatedApprover("Alice", "Dave", "REFERENCE2")
+	if ap.IsFileApproved("b/test.go") {
+		t.Errorf("Expected a delegated approval from an owner not to approve files of a non-owner")
+	}
+}
+
+func TestRemoveApproverForPath(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Alice", "REFERENCE", "")
+	if !ap.IsApproved() {
+		t.Fatalf("Expected the PR to be approved")
+	}
+
+	ap.RemoveApproverForPath("alice", "a/")
+	if expected, found := sets.NewString("a"), ap.UnapprovedFiles(); !expected.Equal(found) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, found)
+	}
+	if ap.IsFileApproved("a/test.go") {
+		t.Errorf("Expected a/test.go not to be approved")
+	}
+	if !ap.IsFileApproved("b/test.go") {
+		t.Errorf("Expected b/test.go to still be approved")
+	}
+	if !ap.GetCurrentApproversSet().Has("Alice") {
+		t.Errorf("Expected Alice to still be an approver")
+	}
+	if expected, found := []string{"Art"}, ap.GetCCs(); !reflect.DeepEqual(expected, found) {
+		t.Errorf("Expected CCs for the cancelled path: %v. Found %v", expected, found)
+	}
+
+	ap.AddApprover("Alice", "REFERENCE", "")
+	if !ap.IsApproved() {
+		t.Errorf("Expected approving again to restore the approval")
+	}
+}
+
+func TestAddApproverForFile(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApproverForFile("Alice", "a/test.go", "https://github.com/org/repo/pull/1#discussion_r1")
+	if expected, found := sets.NewString("b"), ap.UnapprovedFiles(); !expected.Equal(found) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, found)
+	}
+	if !ap.IsFileApproved("a/test.go") {
+		t.Errorf("Expected a/test.go to be approved")
+	}
+	if ap.IsFileApproved("b/test.go") {
+		t.Errorf("Expected b/test.go not to be approved")
+	}
+	if _, ok := ap.SingleApproverCovered(); ok {
+		t.Errorf("Expected a per-file approval not to cover the whole PR")
+	}
+	if expected, found := []string{"Bill"}, ap.GetCCs(); !reflect.DeepEqual(expected, found) {
+		t.Errorf("Expected CCs for the files out of the scope: %v. Found %v", expected, found)
+	}
+
+	ap.AddApproverForFile("Alice", "b/test.go", "https://github.com/org/repo/pull/1#discussion_r2")
+	if !ap.IsApproved() {
+		t.Errorf("Expected per-file approvals of every OWNERS file to approve the PR")
+	}
+
+	// A per-file approval doesn't narrow an approval of the whole PR.
+	ap = NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Alice", "https://github.com/org/repo/pull/1#issuecomment-1", "")
+	ap.AddApproverForFile("alice", "a/test.go", "https://github.com/org/repo/pull/1#discussion_r1")
+	if !ap.IsApproved() {
+		t.Errorf("Expected the PR to still be approved")
+	}
+	for _, how := range []How{HowLGTM, HowAuthorSelfApproved} {
+		ap = NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		ap.addApproval(Approval{Login: "Alice", How: how, Reference: "REFERENCE"})
+		ap.AddApproverForFile("Alice", "a/test.go", "https://github.com/org/repo/pull/1#discussion_r1")
+		if !ap.IsApproved() {
+			t.Errorf("Expected a per-file approval not to narrow an approval %q of the whole PR", how)
+		}
+	}
+
+	// A per-file approval lifts the cancellation of the file only.
+	ap = NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Alice", "https://github.com/org/repo/pull/1#issuecomment-1", "")
+	ap.RemoveApproverForPath("Alice", "a")
+	ap.RemoveApproverForPath("Alice", "b")
+	ap.AddApproverForFile("Alice", "a/test.go", "https://github.com/org/repo/pull/1#discussion_r1")
+	if expected, found := sets.NewString("b"), ap.UnapprovedFiles(); !expected.Equal(found) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, found)
+	}
+
+	// Saving the state keeps the scope.
+	ap = NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApproverForFile("Alice", "a/test.go", "https://github.com/org/repo/pull/1#discussion_r1")
+	data, err := ap.MarshalState()
+	if err != nil {
+		t.Fatalf("Failed to marshal state: %v", err)
+	}
+	restored, err := LoadState(ap.owners, data)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if expected, found := sets.NewString("b"), restored.UnapprovedFiles(); !expected.Equal(found) {
+		t.Errorf("Expected restored unapproved files: %v. Found %v", expected, found)
+	}
+}
+
+func TestInvalidateStaleApprovals(t *testing.T) {
+	tests := []struct {
+		testName          string
+		invalidateStale   bool
+		headSHA           string
+		expectedApprovers sets.String
+	}{
+		{
+			testName:          "Same Head Keeps Approvals",
+			invalidateStale:   true,
+			headSHA:           "sha1",
+			expectedApprovers: sets.NewString("Alice", "Bill", "Carol"),
+		},
+		{
+			testName:          "New Head Drops Stale Approvals",
+			invalidateStale:   true,
+			headSHA:           "sha2",
+			expectedApprovers: sets.NewString("Carol"),
+		},
+		{
+			testName:          "New Head Without Flag Keeps Approvals",
+			invalidateStale:   false,
+			headSHA:           "sha2",
+			expectedApprovers: sets.NewString("Alice", "Bill", "Carol"),
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice", "Bill", "Carol")}), seed: TEST_SEED})
+		ap.InvalidateStale = test.invalidateStale
+		ap.AddApprover("Alice", "REFERENCE", "sha1")
+		ap.AddLGTMer("Bill", "REFERENCE", "sha1")
+		ap.AddApprover("Carol", "REFERENCE", "")
+		ap.InvalidateStaleApprovals(test.headSHA)
+		if calculated := ap.GetCurrentApproversSet(); !test.expectedApprovers.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected approvers: %v. Found %v", test.testName, test.expectedApprovers, calculated)
+		}
+	}
+}
+
+func TestUnapprovedButOwned(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString(),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Carl", "REFERENCE", "")
+
+	if expected, calculated := sets.NewString("a", "b"), ap.UnapprovedFiles(); !expected.Equal(calculated) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, calculated)
+	}
+	if expected, calculated := sets.NewString("b"), ap.UnapprovedButOwned(); !expected.Equal(calculated) {
+		t.Errorf("Expected files waiting on an approver: %v. Found %v", expected, calculated)
+	}
+}
+
+func TestDeadlockedFiles(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Art", "Bill"),
+		"c": sets.NewString("Art", "Carl", "Chris"),
+		"d": sets.NewString("Dan"),
+	})
+	fakeRepo.RequiredApprovalsMap = map[string]int{"c": 3}
+	tests := []struct {
+		testName            string
+		author              string
+		selfApprovalAllowed bool
+		expectedDeadlocked  sets.String
+	}{
+		{
+			testName:           "Sole author-owned file",
+			author:             "art",
+			expectedDeadlocked: sets.NewString("a", "c"),
+		},
+		{
+			testName:            "Self-approval allowed",
+			author:              "Art",
+			selfApprovalAllowed: true,
+			expectedDeadlocked:  sets.NewString(),
+		},
+		{
+			testName:           "Author owns no file",
+			author:             "Eve",
+			expectedDeadlocked: sets.NewString(),
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go"}, repo: fakeRepo, seed: TEST_SEED})
+		testApprovers.SelfApprovalAllowed = test.selfApprovalAllowed
+		if calculated := testApprovers.DeadlockedFiles(test.author); !test.expectedDeadlocked.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected deadlocked files: %v. Found %v", test.testName, test.expectedDeadlocked, calculated)
+		}
+	}
+}
+
+func TestApplyAuthorSelfApproval(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Bill", "Ben"),
+	}
+	tests := []struct {
+		testName            string
+		filenames           []string
+		author              string
+		selfApprovalAllowed bool
+		expectedAdded       bool
+		expectedUnapproved  sets.String
+	}{
+		{
+			testName:            "Allowed and Author Owns a File",
+			filenames:           []string{"a/test.go", "b/test.go"},
+			author:              "art",
+			selfApprovalAllowed: true,
+			expectedAdded:       true,
+			expectedUnapproved:  sets.NewString("b"),
+		},
+		{
+			testName:            "Forbidden and Author Owns a File",
+			filenames:           []string{"a/test.go", "b/test.go"},
+			author:              "Art",
+			selfApprovalAllowed: false,
+			expectedAdded:       false,
+			expectedUnapproved:  sets.NewString("a", "b"),
+		},
+		{
+			testName:            "Allowed and Author Owns None of the Files",
+			filenames:           []string{"b/test.go"},
+			author:              "Art",
+			selfApprovalAllowed: true,
+			expectedAdded:       false,
+			expectedUnapproved:  sets.NewString("b"),
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.SelfApprovalAllowed = test.selfApprovalAllowed
+		if added := testApprovers.ApplyAuthorSelfApproval(test.author, "REFERENCE"); added != test.expectedAdded {
+			t.Errorf("Failed for test %v.  Expected self approval added: %v. Found %v", test.testName, test.expectedAdded, added)
+		}
+		if added := testApprovers.GetCurrentApproversSet().Has(test.author); added != test.expectedAdded {
+			t.Errorf("Failed for test %v.  Expected author in approvers: %v. Found %v", test.testName, test.expectedAdded, added)
+		}
+		if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, calculated)
+		}
+	}
+}
+
+func TestFilesCoveredBy(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Anne", "Zed"),
+		"b": sets.NewString("Bill", "Zed"),
+		"c": sets.NewString("Carl", "Zed"),
+		"d": sets.NewString("Dan"),
+	}
+	tests := []struct {
+		testName        string
+		login           string
+		expectedCovered sets.String
+	}{
+		{
+			testName:        "Covers Multiple Unapproved Files",
+			login:           "zed",
+			expectedCovered: sets.NewString("b", "c"),
+		},
+		{
+			testName:        "Covers Only Approved Files",
+			login:           "Anne",
+			expectedCovered: sets.NewString(),
+		},
+		{
+			testName:        "Covers No File",
+			login:           "Nobody",
+			expectedCovered: sets.NewString(),
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.AddApprover("Anne", "REFERENCE", "")
+		if calculated := testApprovers.FilesCoveredBy(test.login); !test.expectedCovered.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected covered files: %v. Found %v", test.testName, test.expectedCovered, calculated)
+		}
+	}
+}
+
+func TestWouldApprove(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	tests := []struct {
+		testName          string
+		currentApprovers  []string
+		logins            []string
+		expectedApproved  bool
+		expectedRemaining sets.String
+	}{
+		{
+			testName:          "Completes Approval",
+			currentApprovers:  []string{"Art"},
+			logins:            []string{"bill"},
+			expectedApproved:  true,
+			expectedRemaining: sets.NewString(),
+		},
+		{
+			testName:          "Root Approver Completes Approval",
+			logins:            []string{"Alice"},
+			expectedApproved:  true,
+			expectedRemaining: sets.NewString(),
+		},
+		{
+			testName:          "Does Not Complete Approval",
+			logins:            []string{"Art"},
+			expectedApproved:  false,
+			expectedRemaining: sets.NewString("b"),
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		for _, approver := range test.currentApprovers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		if calculated := testApprovers.WouldApprove(test.logins...); calculated != test.expectedApproved {
+			t.Errorf("Failed for test %v.  Expected WouldApprove: %v. Found %v", test.testName, test.expectedApproved, calculated)
+		}
+		if calculated := testApprovers.RemainingAfter(test.logins...); !test.expectedRemaining.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected remaining files: %v. Found %v", test.testName, test.expectedRemaining, calculated)
+		}
+		if expected := sets.NewString(test.currentApprovers...); !expected.Equal(testApprovers.GetCurrentApproversSet()) {
+			t.Errorf("Failed for test %v.  Expected approvers to stay %v. Found %v", test.testName, expected, testApprovers.GetCurrentApproversSet())
+		}
+	}
+}
+
+func TestApprovalProgress(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a":   sets.NewString("Anne"),
+		"a/b": sets.NewString("Bill"),
+		"c":   sets.NewString("Carl"),
+	}
+	tests := []struct {
+		testName         string
+		approvers        []string
+		expectedApproved int
+	}{
+		{
+			testName:         "Nothing Approved",
+			expectedApproved: 0,
+		},
+		{
+			testName:         "Partially Approved",
+			approvers:        []string{"Carl"},
+			expectedApproved: 1,
+		},
+		{
+			testName:         "Fully Approved",
+			approvers:        []string{"Anne", "Carl"},
+			expectedApproved: 2,
+		},
+	}
+
+	for _, test := range tests {
+		// a/b is merged into a in the owners set.
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "a/b/test.go", "a/b/other.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		approved, total := testApprovers.ApprovalProgress()
+		if approved != test.expectedApproved || total != 2 {
+			t.Errorf("Failed for test %v.  Expected %v of 2 approved. Found %v of %v", test.testName, test.expectedApproved, approved, total)
+		}
+	}
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Anne", "Art", "Amy"),
+		"b": sets.NewString("Bill"),
+	})
+	fakeRepo.RequiredApprovalsMap = map[string]int{"a": 2}
+	tests := []struct {
+		testName           string
+		approvers          []string
+		expectedUnapproved sets.String
+		expectedCCs        int
+	}{
+		{
+			testName:           "One of Two Approvals",
+			approvers:          []string{"Anne", "Bill"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedCCs:        1,
+		},
+		{
+			testName:           "Two of Two Approvals",
+			approvers:          []string{"Anne", "Art", "Bill"},
+			expectedUnapproved: sets.NewString(),
+			expectedCCs:        0,
+		},
+		{
+			testName:           "No Approvals",
+			expectedUnapproved: sets.NewString("a", "b"),
+			expectedCCs:        3,
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: fakeRepo, seed: TEST_SEED})
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, calculated)
+		}
+		if approved := testApprovers.IsFileApproved("a/test.go"); approved != !test.expectedUnapproved.Has("a") {
+			t.Errorf("Failed for test %v.  Expected a/test.go approved: %v. Found %v", test.testName, !test.expectedUnapproved.Has("a"), approved)
+		}
+		if calculated := testApprovers.GetCCs(); len(calculated) != test.expectedCCs {
+			t.Errorf("Failed for test %v.  Expected %v CCs. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestRequiredApprovers(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Anne", "Art", "Sam"),
+		"b": sets.NewString("Bill"),
+	})
+	fakeRepo.RequiredApproversMap = map[string]sets.String{"a": sets.NewString("Sam")}
+	tests := []struct {
+		testName           string
+		approvers          []string
+		assignees          []string
+		excluded           []string
+		expectedUnapproved sets.String
+		expectedFiles      []string
+		expectedCCs        []string
+	}{
+		{
+			testName:           "Covered without required approver",
+			approvers:          []string{"Anne", "Bill"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** needs approval from required approver [Sam]\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+			expectedCCs: []string{"Sam"},
+		},
+		{
+			testName:           "Required approver approved",
+			approvers:          []string{"sam", "Bill"},
+			expectedUnapproved: sets.NewString(),
+			expectedFiles: []string{
+				"- ~~[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)~~ [sam]\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+			expectedCCs: []string{},
+		},
+		{
+			testName:           "No approvals",
+			expectedUnapproved: sets.NewString("a", "b"),
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** needs approval from required approver [Sam]\n",
+				"- **[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)**\n",
+			},
+			expectedCCs: []string{"Bill", "Sam"},
+		},
+		{
+			testName:           "Assigned required approver",
+			approvers:          []string{"Anne", "Bill"},
+			assignees:          []string{"Sam"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** needs approval from required approver [Sam]\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+			expectedCCs: []string{"Sam"},
+		},
+		{
+			testName:           "Excluded required approver",
+			approvers:          []string{"Anne", "Bill"},
+			excluded:           []string{"Sam"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedFiles: []string{
+				"- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)** needs approval from required approver [Sam]\n",
+				"- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]\n",
+			},
+			expectedCCs: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: fakeRepo, seed: TEST_SEED})
+		testApprovers.ExcludedApprovers = sets.NewString(test.excluded...)
+		testApprovers.AddAssignees(test.assignees...)
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, calculated)
+		}
+		if approved := testApprovers.IsFileApproved("a/test.go"); approved != !test.expectedUnapproved.Has("a") {
+			t.Errorf("Failed for test %v.  Expected a/test.go approved: %v. Found %v", test.testName, !test.expectedUnapproved.Has("a"), approved)
+		}
+		if approved := testApprovers.IsApproved(); approved != (test.expectedUnapproved.Len() == 0) {
+			t.Errorf("Failed for test %v.  Expected approved: %v. Found %v", test.testName, test.expectedUnapproved.Len() == 0, approved)
+		}
+		calculatedFiles := []string{}
+		for _, file := range testApprovers.GetFiles("org", "project", "") {
+			calculatedFiles = append(calculatedFiles, file.String())
+		}
+		if !reflect.DeepEqual(test.expectedFiles, calculatedFiles) {
+			t.Errorf("Failed for test %v.  Expected files: %v. Found %v", test.testName, test.expectedFiles, calculatedFiles)
+		}
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(test.expectedCCs, calculated) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestIsApproved(t *testing.T) {
+	rootApprovers := sets.NewString("Alice", "Bob")
+	aApprovers := sets.NewString("Art", "Anne")
+	bApprovers := sets.NewString("Bill", "Ben", "Barbara")
+	cApprovers := sets.NewString("Chris", "Carol")
+	dApprovers := sets.NewString("David", "Dan", "Debbie")
+	eApprovers := sets.NewString("Eve", "Erin")
+	edcApprovers := eApprovers.Union(dApprovers).Union(cApprovers)
+	FakeRepoMap := map[string]sets.String{
+		"":        rootApprovers,
+		"a":       aApprovers,
+		"b":       bApprovers,
+		"c":       cApprovers,
+		"a/d":     dApprovers,
+		"a/combo": edcApprovers,
+	}
+	tests := []struct {
+		testName          string
+		filenames         []string
+		currentlyApproved sets.String
+		testSeed          int64
+		isApproved        bool
+	}{
+		{
+			testName:          "Empty PR",
+			filenames:         []string{},
+			currentlyApproved: sets.NewString(),
+			testSeed:          0,
+			isApproved:        true,
+		},
+		{
+			testName:          "Single Root File PR Approved",
+			filenames:         []string{"kubernetes.go"},
+			currentlyApproved: sets.NewString(rootApprovers.List()[0]),
+			testSeed:          3,
+			isApproved:        true,
+		},
+		{
+			testName:          "Single Root File PR No One Approved",
+			filenames:         []string{"kubernetes.go"},
+			testSeed:          0,
+			currentlyApproved: sets.NewString(),
+			isApproved:        false,
+		},
+		{
+			testName:          "Combo and Other; Neither Approved",
+			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
+			testSeed:          0,
+			currentlyApproved: sets.NewString(),
+			isApproved:        false,
+		},
+		{
+			testName:          "Combo and Other; Both Approved",
+			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
+			testSeed:          0,
+			currentlyApproved: edcApprovers.Intersection(dApprovers),
+			isApproved:        true,
+		},
+		{
+			testName:          "A, B, C; Nothing Approved",
+			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
+			testSeed:          0,
+			currentlyApproved: sets.NewString(),
+			isApproved:        false,
+		},
+		{
+			testName:          "A, B, C; Partially Approved",
+			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
+			testSeed:          0,
+			currentlyApproved: aApprovers.Union(bApprovers),
+			isApproved:        false,
+		},
+		{
+			testName:          "A, B, C; Approved At the Root",
+			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
+			testSeed:          0,
+			currentlyApproved: rootApprovers,
+			isApproved:        true,
+		},
+		{
+			testName:          "A, B, C; Approved At the Leaves",
+			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
+			testSeed:          0,
+			currentlyApproved: sets.NewString("Anne", "Ben", "Carol"),
+			isApproved:        true,
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: test.testSeed})
+		for approver := range test.currentlyApproved {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		calculated := testApprovers.IsApproved()
+		if test.isApproved != calculated {
+			t.Errorf("Failed for test %v.  Expected Approval Status: %v. Found %v", test.testName, test.isApproved, calculated)
+		}
+	}
+}
+
+func TestApproversForFile(t *testing.T) {
+	rootApprovers := sets.NewString("Alice", "Bob")
+	aApprovers := sets.NewString("Art", "Anne")
+	dApprovers := sets.NewString("David", "Dan", "Debbie")
+	FakeRepoMap := map[string]sets.String{
+		"":    rootApprovers,
+		"a":   aApprovers,
+		"a/d": dApprovers,
+	}
+	tests := []struct {
+		testName          string
+		filenames         []string
+		currentlyApproved sets.String
+		path              string
+		expectedApprovers sets.String
+		isApproved        bool
+	}{
+		{
+			testName:          "Root File Not Approved",
+			filenames:         []string{"kubernetes.go"},
+			currentlyApproved: sets.NewString(),
+			path:              "kubernetes.go",
+			expectedApprovers: rootApprovers,
+			isApproved:        false,
+		},
+		{
+			testName:          "Leaf File Approved at the Root",
+			filenames:         []string{"a/d/test.go"},
+			currentlyApproved: sets.NewString("Alice"),
+			path:              "a/d/test.go",
+			expectedApprovers: rootApprovers.Union(aApprovers).Union(dApprovers),
+			isApproved:        true,
+		},
+		{
+			testName:          "Leaf File Approved Next to its Parent",
+			filenames:         []string{"a/test.go", "a/d/test.go"},
+			currentlyApproved: sets.NewString("Dan"),
+			path:              "a/d/test.go",
+			expectedApprovers: rootApprovers.Union(aApprovers).Union(dApprovers),
+			isApproved:        true,
+		},
+		{
+			testName:          "Parent File Not Approved by Leaf Approver",
+			filenames:         []string{"a/test.go", "a/d/test.go"},
+			currentlyApproved: sets.NewString("Dan"),
+			path:              "a/test.go",
+			expectedApprovers: rootApprovers.Union(aApprovers),
+			isApproved:        false,
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		for approver := range test.currentlyApproved {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		if calculated := testApprovers.ApproversForFile(test.path); !calculated.Equal(test.expectedApprovers) {
+			t.Errorf("Failed for test %v.  Expected approvers: %v. Found %v", test.testName, test.expectedApprovers, calculated)
+		}
+		if calculated := testApprovers.IsFileApproved(test.path); calculated != test.isApproved {
+			t.Errorf("Failed for test %v.  Expected file approval: %v. Found %v", test.testName, test.isApproved, calculated)
+		}
+	}
+}
+
+func TestCaseSensitive(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"c": sets.NewString("Carl"),
+		"d": sets.NewString("Bob", "Dan"),
+	}
+	tests := []struct {
+		testName         string
+		caseSensitive    bool
+		filenames        []string
+		approvers        []string
+		assignees        []string
+		expectedApproved bool
+		expectedCCs      []string
+	}{
+		{
+			testName:         "Case mismatch, case-insensitive",
+			filenames:        []string{"a/test.go"},
+			approvers:        []string{"art"},
+			expectedApproved: true,
+			expectedCCs:      []string{},
+		},
+		{
+			testName:         "Case mismatch, case-sensitive",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go"},
+			approvers:        []string{"art"},
+			expectedApproved: false,
+			expectedCCs:      []string{"Art"},
+		},
+		{
+			testName:         "Same case, case-sensitive",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go"},
+			approvers:        []string{"Art"},
+			expectedApproved: true,
+			expectedCCs:      []string{},
+		},
+		{
+			testName:         "Case mismatch, case-sensitive, several OWNERS files",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go", "c/test.go"},
+			approvers:        []string{"art"},
+			expectedApproved: false,
+			expectedCCs:      []string{"Art", "Carl"},
+		},
+		{
+			testName:         "Non-owner differing by case approves after the owner, case-sensitive",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go"},
+			approvers:        []string{"Art", "art"},
+			expectedApproved: true,
+			expectedCCs:      []string{},
+		},
+		{
+			testName:         "Assignee differing by case from the approver, case-sensitive",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go"},
+			approvers:        []string{"Art"},
+			assignees:        []string{"art"},
+			expectedApproved: true,
+			expectedCCs:      []string{},
+		},
+		{
+			testName:         "Assignee differing by case from the approver, case-sensitive, several OWNERS files",
+			caseSensitive:    true,
+			filenames:        []string{"a/test.go", "d/test.go"},
+			approvers:        []string{"Dan"},
+			assignees:        []string{"dan"},
+			expectedApproved: false,
+			expectedCCs:      []string{"Art"},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.CaseSensitive = test.caseSensitive
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		testApprovers.AddAssignees(test.assignees...)
+		if approved := testApprovers.IsApproved(); approved != test.expectedApproved {
+			t.Errorf("Failed for test %v.  Expected approved: %v. Found %v", test.testName, test.expectedApproved, approved)
+		}
+		if approved := testApprovers.IsFileApproved("a/test.go"); approved != test.expectedApproved {
+			t.Errorf("Failed for test %v.  Expected a/test.go approved: %v. Found %v", test.testName, test.expectedApproved, approved)
+		}
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, test.expectedCCs) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+		data, err := testApprovers.MarshalState()
+		if err != nil {
+			t.Fatalf("Failed for test %v.  MarshalState() failed: %v", test.testName, err)
+		}
+		loaded, err := LoadState(testApprovers.owners, data)
+		if err != nil {
+			t.Fatalf("Failed for test %v.  LoadState() failed: %v", test.testName, err)
+		}
+		if approved := loaded.IsApproved(); approved != test.expectedApproved {
+			t.Errorf("Failed for test %v.  Expected approved after LoadState: %v. Found %v", test.testName, test.expectedApproved, approved)
+		}
+	}
+}
+
+func TestRequireExtraApproverForPath(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"":    sets.NewString("Root"),
+		"a":   sets.NewString("Anne", "Art"),
+		"a/b": sets.NewString("Ben"),
+		"b":   sets.NewString("Bill"),
+	})
+	tests := []struct {
+		testName           string
+		path               string
+		extra              string
+		approvers          []string
+		expectedUnapproved sets.String
+		expectedCCs        []string
+	}{
+		{
+			testName:           "Extra approver not approved",
+			path:               "a/secret.go",
+			extra:              "Anne",
+			approvers:          []string{"Art", "Bill"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedCCs:        []string{"Anne"},
+		},
+		{
+			testName:           "Extra approver approved",
+			path:               "a/secret.go",
+			extra:              "Anne",
+			approvers:          []string{"anne", "Bill"},
+			expectedUnapproved: sets.NewString(),
+			expectedCCs:        []string{},
+		},
+		{
+			testName:           "Root approver without the extra approver",
+			path:               "a/secret.go",
+			extra:              "Anne",
+			approvers:          []string{"Root"},
+			expectedUnapproved: sets.NewString("a"),
+			expectedCCs:        []string{"Anne"},
+		},
+		{
+			testName:           "Extra approver outside of OWNERS",
+			path:               "b/test.go",
+			extra:              "Security",
+			approvers:          []string{"Anne", "Bill"},
+			expectedUnapproved: sets.NewString("b"),
+			expectedCCs:        []string{"Security"},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/secret.go", "b/test.go"}, repo: fakeRepo, seed: TEST_SEED})
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		original := testApprovers
+		testApprovers.RequireExtraApproverForPath(test.path, test.extra)
+		if calculated := testApprovers.UnapprovedFiles(); !test.expectedUnapproved.Equal(calculated) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, calculated)
+		}
+		if approved := testApprovers.IsApproved(); approved != (test.expectedUnapproved.Len() == 0) {
+			t.Errorf("Failed for test %v.  Expected approved: %v. Found %v", test.testName, test.expectedUnapproved.Len() == 0, approved)
+		}
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(test.expectedCCs, calculated) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+		if !original.IsApproved() {
+			t.Errorf("Failed for test %v.  Expected the requirement to only apply to the Approvers it was added to", test.testName)
+		}
+	}
+
+	// The OWNERS file of a/b is approved through the one of a.
+	testApprovers := NewApprovers(Owners{filenames: []string{"a/y.go", "a/b/x.go"}, repo: fakeRepo, seed: TEST_SEED})
+	testApprovers.AddApprover("Art", "REFERENCE", "")
+	testApprovers.RequireExtraApproverForPath("a/b/x.go", "Security")
+	if calculated, expected := testApprovers.UnapprovedFiles(), sets.NewString("a"); !expected.Equal(calculated) {
+		t.Errorf("Expected unapproved files with a nested requirement: %v. Found %v", expected, calculated)
+	}
+	if calculated, expected := testApprovers.GetCCs(), []string{"Security"}; !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected CCs with a nested requirement: %v. Found %v", expected, calculated)
+	}
+	testApprovers.AddApprover("Security", "REFERENCE", "")
+	if !testApprovers.IsApproved() {
+		t.Errorf("Expected the PR to be approved once the nested requirement is met")
+	}
+}
+
+func TestGetFilesApprovers(t *testing.T) {
+	tests := []struct {
+		testName       string
+		filenames      []string
+		approvers      []string
+		owners         map[string]sets.String
+		expectedStatus map[string]sets.String
+	}{
+		{
+			testName:       "Empty PR",
+			filenames:      []string{},
+			approvers:      []string{},
+			owners:         map[string]sets.String{},
+			expectedStatus: map[string]sets.String{},
+		},
+		{
+			testName:       "No approvers",
+			filenames:      []string{"a/a", "c"},
+			approvers:      []string{},
+			owners:         map[string]sets.String{"": sets.NewString("RootOwner")},
+			expectedStatus: map[string]sets.String{"": sets.String{}},
+		},
+		{
+			testName: "Approvers approves some",
+			filenames: []string{
+				"a/a",
+				"c/c",
+			},
+			approvers: []string{"CApprover"},
+			owners: map[string]sets.String{
+				"a": sets.NewString("AApprover"),
+				"c": sets.NewString("CApprover"),
+			},
+			expectedStatus: map[string]sets.String{
+				"a": sets.String{},
+				"c": sets.NewString("CApprover"),
+			},
+		},
+		{
+			testName: "Multiple approvers",
+			filenames: []string{
+				"a/a",
+				"c/c",
+			},
+			approvers: []string{"RootApprover", "CApprover"},
+			owners: map[string]sets.String{
+				"":  sets.NewString("RootApprover"),
+				"a": sets.NewString("AApprover"),
+				"c": sets.NewString("CApprover"),
+			},
+			expectedStatus: map[string]sets.String{
+				"a": sets.NewString("RootApprover"),
+				"c": sets.NewString("RootApprover", "CApprover"),
+			},
+		},
+		{
+			testName:       "Case-insensitive approvers",
+			filenames:      []string{"file"},
+			approvers:      []string{"RootApprover"},
+			owners:         map[string]sets.String{"": sets.NewString("rOOtaPProver")},
+			expectedStatus: map[string]sets.String{"": sets.NewString("RootApprover")},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(test.owners)})
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		calculated := testApprovers.GetFilesApprovers()
+		if !reflect.DeepEqual(test.expectedStatus, calculated) {
+			t.Errorf("Failed for test %v.  Expected approval status: %v. Found %v", test.testName, test.expectedStatus, calculated)
+		}
+	}
+}
+
+func TestApprovalsByFile(t *testing.T) {
+	testApprovers := NewApprovers(Owners{
+		filenames: []string{"a/a.go", "b/b.go", "c/c.go"},
+		repo: createFakeRepo(map[string]sets.String{
+			"a": sets.NewString("Anne"),
+			"b": sets.NewString("anne", "Bill"),
+			"c": sets.NewString("Carl"),
+		}),
+	})
+	testApprovers.AddApprover("Anne", "REFERENCE-1", "")
+	testApprovers.AddLGTMer("Bill", "REFERENCE-2", "")
+
+	expected := map[string][]string{
+		"a": {"Anne/Approved/REFERENCE-1"},
+		"b": {"Anne/Approved/REFERENCE-1", "Bill/LGTM/REFERENCE-2"},
+		"c": {},
+	}
+	calculated := map[string][]string{}
+	for fn, approvals := range testApprovers.ApprovalsByFile() {
+		calculated[fn] = []string{}
+		for _, approval := range approvals {
+			calculated[fn] = append(calculated[fn], fmt.Sprintf("%s/%s/%s", approval.Login, approval.How, approval.Reference))
+		}
+	}
+	if !reflect.DeepEqual(calculated, expected) {
+		t.Errorf("Expected approvals by file: %v. Found %v", expected, calculated)
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.AddApprover("Bill", "https://github.com/org/project/pull/1#issuecomment-1", "")
+
+	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+
+This pull-request has been approved by: *<a href="https://github.com/org/project/pull/1#issuecomment-1" title="Approved">Bill</a>*
+We suggest the following additional approver: **Alice**
+
+Assign the PR to them by writing ` + "`/assign @Alice`" + ` in a comment when ready.
+
+<details open>
+Needs approval from an approver in each of these OWNERS Files:
+
+- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**
+- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]
+
+You can indicate your approval by writing ` + "`/approve`" + ` in a comment
+You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":["Alice"],"files":{"a":[],"b":["Bill"]},"unapproved":["a"],"version":2} -->
+<!-- SUMMARY={"approved":false,"approved_files":1,"total_files":2,"suggested":1} -->`
+	if got := GetMessage(ap, "org", "project", ""); got == nil {
+		t.Error("GetMessage() failed")
+	} else if *got != want {
+		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	}
+}
+
+func TestGetMessageAllApproved(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.AddApprover("Alice", "https://github.com/org/project/pull/1#issuecomment-1", "")
+	ap.AddLGTMer("Bill", "https://github.com/org/project/pull/1#issuecomment-1", "")
+
+	want := `[APPROVALNOTIFIER] This PR is **APPROVED**
+
+This pull-request has been approved by: *<a href="https://github.com/org/project/pull/1#issuecomment-1" title="Approved">Alice</a>*, *<a href="https://github.com/org/project/pull/1#issuecomment-1" title="LGTM">Bill</a>*
+
+<details >
+Needs approval from an approver in each of these OWNERS Files:
+
+- ~~[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)~~ [Alice]
+- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]
+
+You can indicate your approval by writing ` + "`/approve`" + ` in a comment
+You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":[],"files":{"a":["Alice"],"b":["Bill"]},"unapproved":[],"version":2} -->
+<!-- SUMMARY={"approved":true,"approved_files":2,"total_files":2,"suggested":0} -->`
+	if got := GetMessage(ap, "org", "project", ""); got == nil {
+		t.Error("GetMessage() failed")
+	} else if *got != want {
+		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	}
+}
+
+func TestGetMessageNoneApproved(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+
+	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+
+This pull-request has been approved by: 
+We suggest the following additional approvers: **Alice**, **Bill**
+
+Assign the PR to them by writing ` + "`/assign @Alice @Bill`" + ` in a comment when ready.
+
+<details open>
+Needs approval from an approver in each of these OWNERS Files:
+
+- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**
+- **[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)**
+
+You can indicate your approval by writing ` + "`/approve`" + ` in a comment
+You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":["Alice","Bill"],"files":{"a":[],"b":[]},"unapproved":["a","b"],"version":2} -->
+<!-- SUMMARY={"approved":false,"approved_files":0,"total_files":2,"suggested":2} -->`
+	if got := GetMessage(ap, "org", "project", ""); got == nil {
+		t.Error("GetMessage() failed")
+	} else if *got != want {
+		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	}
+}
+
+func TestGetMessageWithReviewers(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Alice"),
+	})
+	fakeRepo.LeafReviewersMap = map[string]sets.String{
+		"a": sets.NewString("Rachel"),
+	}
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo:      fakeRepo,
+		},
+	)
+	ap.SuggestReviewers = true
+
+	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+
+This pull-request has been approved by: 
+We suggest the following additional approver: **Alice**
+
+Assign the PR to them by writing ` + "`/assign @Alice`" + ` in a comment when ready.
+We suggest the following reviewer: **Rachel**
+
+<details open>
+Needs approval from an approver in each of these OWNERS Files:
+
+- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**
+
+You can indicate your approval by writing ` + "`/approve`" + ` in a comment
+You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":["Alice"],"files":{"a":[]},"unapproved":["a"],"version":2} -->
+<!-- SUMMARY={"approved":false,"approved_files":0,"total_files":1,"suggested":1} -->`
+	if got := GetMessage(ap, "org", "project", ""); got == nil {
+		t.Error("GetMessage() failed")
+	} else if *got != want {
+		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	}
+}
+
+func TestGetMessageWithTemplate(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+			}),
+		},
+	)
+
+	tests := []struct {
+		testName string
+		tmpl     string
+		want     string
+	}{
+		{
+			testName: "Custom Template",
+			tmpl:     "Ping {{range .SuggestedCCs}}@{{.}}{{end}}",
+			want:     "[APPROVALNOTIFIER] This PR is **NOT APPROVED**\n\nPing @Alice\n<!-- META={\"approvers\":[\"Alice\"],\"files\":{\"a\":[]},\"unapproved\":[\"a\"],\"version\":2} -->\n<!-- SUMMARY={\"approved\":false,\"approved_files\":0,\"total_files\":1,\"suggested\":1} -->",
+		},
+		{
+			testName: "Empty Template Uses Default",
+			tmpl:     "",
+			want:     *GetMessage(ap, "org", "project", ""),
+		},
+		{
+			testName: "Invalid Template Uses Default",
+			tmpl:     "{{.NoSuchField}}",
+			want:     *GetMessage(ap, "org", "project", ""),
+		},
+	}
+
+	for _, test := range tests {
+		if got := GetMessageWithTemplate(ap, "org", "project", "", test.tmpl); got == nil {
+			t.Errorf("Failed for test %v.  GetMessageWithTemplate() failed", test.testName)
+		} else if *got != test.want {
+			t.Errorf("Failed for test %v.  GetMessageWithTemplate() = %+v, want = %+v", test.testName, *got, test.want)
+		}
+	}
+}
+
+func TestGetMessageDeterministic(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":    sets.NewString("Alice", "Bob"),
+		"a":   sets.NewString("Art", "Anne", "Amy"),
+		"b":   sets.NewString("Bill", "Ben", "Barbara"),
+		"c":   sets.NewString("Chris", "Carol"),
+		"c/d": sets.NewString("David", "Dan"),
+		"e":   sets.NewString("Eve", "Erin", "Bill"),
+	}
+	filenames := []string{"a/test.go", "b/test.go", "c/test.go", "c/d/test.go", "e/test.go"}
+	approvers := []string{"anne", "Anne", "Chris", "dan", "Eve"}
+
+	render := func(reversed bool) (string, []string, []Approval) {
+		ap := NewApprovers(Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		for i := range approvers {
+			approver := approvers[i]
+			if reversed {
+				approver = approvers[len(approvers)-1-i]
+			}
+			ap.AddApprover(approver, "REFERENCE", "")
+		}
+		ap.AddAssignees("Ben", "Barbara", "Erin")
+		return *GetMessage(ap, "org", "project", ""), ap.GetCCs(), ap.ListApprovals()
+	}
+
+	expectedMessage, expectedCCs, _ := render(false)
+	for i := 0; i < 20; i++ {
+		message, ccs, _ := render(false)
+		if message != expectedMessage {
+			t.Fatalf("Expected identical messages across runs. Found:\n%v\nand:\n%v", expectedMessage, message)
+		}
+		if !reflect.DeepEqual(expectedCCs, ccs) {
+			t.Fatalf("Expected identical CCs across runs. Found %v and %v", expectedCCs, ccs)
+		}
+	}
+
+	_, reversedCCs, reversedApprovals := render(true)
+	if !reflect.DeepEqual(expectedCCs, reversedCCs) {
+		t.Errorf("Expected the CCs not to depend on the approval order. Found %v and %v", expectedCCs, reversedCCs)
+	}
+	logins := []string{}
+	for _, approval := range reversedApprovals {
+		logins = append(logins, approval.Login)
+	}
+	if expected := []string{"Chris", "Eve", "anne", "dan"}; !reflect.DeepEqual(expected, logins) {
+		t.Errorf("Expected approvals sorted by login %v. Found %v", expected, logins)
+	}
+}
+
+func TestConcurrentAddApprover(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Alice", "Art", "Anne", "Amy"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	logins := []string{"Alice", "Art", "Anne", "Amy"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, login := range logins {
+			wg.Add(1)
+			go func(login string) {
+				defer wg.Done()
+				ap.AddApprover(login, "", "")
+				ap.AddAssignees(login)
+				ap.AddHold(login)
+				ap.RemoveHold(login)
+			}(login)
+		}
+	}
+	wg.Wait()
+
+	if expected, found := sets.NewString(logins...), ap.GetCurrentApproversSet(); !expected.Equal(found) {
+		t.Errorf("Expected approvers %v. Found %v", expected, found)
+	}
+	if expected, found := sets.NewString(logins...), ap.assignees; !expected.Equal(found) {
+		t.Errorf("Expected assignees %v. Found %v", expected, found)
+	}
+	if ap.IsBlocked() {
+		t.Errorf("Expected every hold to be removed")
+	}
+}
+
+func TestConcurrentGetCCs(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Alice"),
+		"b": sets.NewString("Bill"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Alice", "REFERENCE", "")
+
+	var wg sync.WaitGroup
+	found := make([][]string, 10)
+	for i := range found {
+		wg.Add(1)
+		// Copies share the cached CCs.
+		go func(i int, copied Approvers) {
+			defer wg.Done()
+			found[i] = copied.GetCCs()
+		}(i, ap)
+	}
+	wg.Wait()
+
+	for _, ccs := range found {
+		if expected := []string{"Bill"}; !reflect.DeepEqual(expected, ccs) {
+			t.Errorf("Expected CCs %v. Found %v", expected, ccs)
+		}
+	}
+}
+
+func TestHolds(t *testing.T) {
+	ap := NewApprovers(Owners{filenames: []string{"a/a.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}), seed: TEST_SEED})
+	ap.AddApprover("Alice", "REFERENCE", "")
+	if ap.IsBlocked() {
+		t.Errorf("Expected the PR not to be blocked without holds")
+	}
+
+	ap.AddHold("Bob")
+	if !ap.IsApproved() || !ap.IsBlocked() {
+		t.Errorf("Expected the hold to block the approved PR. Approved: %v, blocked: %v", ap.IsApproved(), ap.IsBlocked())
+	}
+	if message := GetMessage(ap, "org", "project", ""); message == nil || !strings.Contains(*message, "On hold by **Bob**, this PR must not merge until the hold is removed.") {
+		t.Errorf("Expected the message to mention the hold. Found %v", message)
+	}
+	if message := GetMessage(ap, "org", "project", ""); message == nil || !strings.HasPrefix(*message, "[APPROVALNOTIFIER] This PR is **APPROVED**, **ON HOLD**\n") || !strings.Contains(*message, `"on_hold":true`) {
+		t.Errorf("Expected the title and the summary to show the hold. Found %v", message)
+	}
+	if message := GetPlainTextMessage(ap, "org", "project"); message == nil || !strings.HasPrefix(*message, "This PR is APPROVED, ON HOLD\n") {
+		t.Errorf("Expected the plain text title to show the hold. Found %v", message)
+	}
+
+	ap.RemoveHold("bob")
+	if ap.IsBlocked() {
+		t.Errorf("Expected the PR not to be blocked once the hold is removed")
+	}
+	if message := GetMessage(ap, "org", "project", ""); message == nil || strings.Contains(*message, "On hold") || strings.Contains(*message, "ON HOLD") {
+		t.Errorf("Expected the message not to mention holds. Found %v", message)
+	}
+}
+
+func TestGetMessageWithOptionsE(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+			}),
+		},
+	)
+
+	for _, tmpl := range []string{"{{if}}", "{{.NoSuchField}}"} {
+		if got, err := GetMessageWithOptionsE(ap, "org", "project", "", MessageOptions{Template: tmpl}); err == nil {
+			t.Errorf("Expected an error rendering %q. Found %q", tmpl, got)
+		}
+		if _, err := GenerateTemplate(tmpl, "message", ApprovalState{}); err == nil {
+			t.Errorf("Expected GenerateTemplate() to fail rendering %q", tmpl)
+		}
+	}
+
+	got, err := GetMessageE(ap, "org", "project", "")
+	if err != nil {
+		t.Fatalf("GetMessageE() failed: %v", err)
+	}
+	if want := GetMessage(ap, "org", "project", ""); want == nil || got != *want {
+		t.Errorf("GetMessageE() = %q, want = %v", got, want)
+	}
+}
+
+func TestGetPlainTextMessage(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+			seed: TEST_SEED,
+		},
+	)
+	ap.AddApprover("Alice", "https://github.com/org/project/pull/1#issuecomment-1", "")
+
+	message := GetPlainTextMessage(ap, "org", "project")
+	if message == nil {
+		t.Fatalf("Expected a plain text message")
+	}
+	for _, expected := range []string{
+		"This PR is NOT APPROVED",
+		"approved by: Alice",
+		"additional approver: Bill",
+		"- a/OWNERS (https://github.com/org/project/blob/master/a/OWNERS) approved by Alice",
+		"- b/OWNERS (https://github.com/org/project/blob/master/b/OWNERS) needs approval",
+	} {
+		if !strings.Contains(*message, expected) {
+			t.Errorf("Expected %q in the plain text message. Found %q", expected, *message)
+		}
+	}
+	for _, markdown := range []string{"~~", "**", "<a href", "](", "<details"} {
+		if strings.Contains(*message, markdown) {
+			t.Errorf("Expected no markdown %q in the plain text message. Found %q", markdown, *message)
+		}
+	}
+
+	ap.AddApprover("Bill", "https://github.com/org/project/pull/1#issuecomment-2", "")
+	message = GetPlainTextMessage(ap, "org", "project")
+	if message == nil || !strings.Contains(*message, "This PR is APPROVED") {
+		t.Errorf("Expected the plain text message to be approved. Found %v", message)
+	}
+	if strings.Contains(*message, "We suggest") {
+		t.Errorf("Expected no suggestion once approved. Found %q", *message)
+	}
+}
+
+func TestGetMessageWithOptions(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+			}),
+		},
+	)
+
+	tests := []struct {
+		testName            string
+		opts                MessageOptions
+		expectedSuggestions bool
+	}{
+		{
+			testName:            "Default Options",
+			opts:                MessageOptions{},
+			expectedSuggestions: true,
+		},
+		{
+			testName:            "Omit Suggestions",
+			opts:                MessageOptions{OmitSuggestions: true},
+			expectedSuggestions: false,
+		},
+	}
+
+	for _, test := range tests {
+		got := GetMessageWithOptions(ap, "org", "project", "", test.opts)
+		if got == nil {
+			t.Fatalf("Failed for test %v.  GetMessageWithOptions() failed", test.testName)
+		}
+		for _, section := range []string{"We suggest the following additional approver", "/assign"} {
+			if strings.Contains(*got, section) != test.expectedSuggestions {
+				t.Errorf("Failed for test %v.  Expected %q in the message: %v. Found %v", test.testName, section, test.expectedSuggestions, *got)
+			}
+		}
+		for _, section := range []string{"**NOT APPROVED**", "a/OWNERS", "/approve"} {
+			if !strings.Contains(*got, section) {
+				t.Errorf("Failed for test %v.  Expected %q in the message. Found %v", test.testName, section, *got)
+			}
+		}
+	}
+	if got, want := GetMessageWithOptions(ap, "org", "project", "", MessageOptions{}), GetMessage(ap, "org", "project", ""); *got != *want {
+		t.Errorf("Expected the default options to render GetMessage() = %v. Found %v", *want, *got)
+	}
+}
+
+func TestGroupFilesByArea(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/b/b.go", "a/c/c.go", "d/d.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a/b": sets.NewString("Bob"),
+				"a/c": sets.NewString("Carl"),
+				"d":   sets.NewString("Dan"),
+			}),
+		},
+	)
+	ap.AddApprover("Bob", "REFERENCE", "")
+
+	groups := GroupFilesByArea(ap.GetFiles("org", "project", ""))
+	expectedAreas := []string{"a", "d"}
+	expectedFiles := [][]string{{"ApprovedFile", "UnapprovedFile"}, {"UnapprovedFile"}}
+	if len(groups) != len(expectedAreas) {
+		t.Fatalf("Expected %d groups. Found %v", len(expectedAreas), groups)
+	}
+	for i, group := range groups {
+		if group.Area != expectedAreas[i] {
+			t.Errorf("Expected group %d to be %q. Found %q", i, expectedAreas[i], group.Area)
+		}
+		found := []string{}
+		for _, file := range group.Files {
+			found = append(found, reflect.TypeOf(file).Name())
+		}
+		if !reflect.DeepEqual(found, expectedFiles[i]) {
+			t.Errorf("Failed for area %q.  Expected files: %v. Found %v", group.Area, expectedFiles[i], found)
+		}
+	}
+
+	message := GetMessageWithOptions(ap, "org", "project", "", MessageOptions{GroupFilesByArea: true})
+	if message == nil {
+		t.Fatalf("GetMessageWithOptions() failed")
+	}
+	for _, section := range []string{"**a**\n\n- ~~[a/b/OWNERS]", "- **[a/c/OWNERS]", "**d**\n\n- **[d/OWNERS]"} {
+		if !strings.Contains(*message, section) {
+			t.Errorf("Expected %q in the message. Found %v", section, *message)
+		}
+	}
+	if strings.Contains(*GetMessage(ap, "org", "project", ""), "**a**") {
+		t.Errorf("Expected no groups in the default message")
+	}
+	if area := fileArea(MissingLabelFile{ApprovedFile{filepath: ""}, nil}); area != "/" {
+		t.Errorf("Expected the root OWNERS file in the %q area. Found %q", "/", area)
+	}
+}
+
+func TestGetMessageNotificationName(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+			}),
+		},
+	)
+
+	tests := []struct {
+		testName       string
+		name           string
+		expectedPrefix string
+	}{
+		{
+			testName:       "Default Name",
+			expectedPrefix: "[APPROVALNOTIFIER] This PR is **NOT APPROVED**\n",
+		},
+		{
+			testName:       "Custom Name",
+			name:           "DocsApprovalNotifier",
+			expectedPrefix: "[DOCSAPPROVALNOTIFIER] This PR is **NOT APPROVED**\n",
+		},
+	}
+
+	for _, test := range tests {
+		got := GetMessageWithOptions(ap, "org", "project", "", MessageOptions{NotificationName: test.name})
+		if got == nil {
+			t.Fatalf("Failed for test %v.  GetMessageWithOptions() failed", test.testName)
+		}
+		if !strings.HasPrefix(*got, test.expectedPrefix) {
+			t.Errorf("Failed for test %v.  Expected the notification to start with %q. Found %v", test.testName, test.expectedPrefix, *got)
+		}
+	}
+}
+
+func TestSingleApproverCovered(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	tests := []struct {
+		testName         string
+		approvers        []string
+		expectedApprover string
+		expectedCovered  bool
+	}{
+		{
+			testName:        "No approvers",
+			expectedCovered: false,
+		},
+		{
+			testName:         "Single approver covering all files",
+			approvers:        []string{"Alice", "Art"},
+			expectedApprover: "Alice",
+			expectedCovered:  true,
+		},
+		{
+			testName:        "Two approvers needed",
+			approvers:       []string{"Art", "Bill"},
+			expectedCovered: false,
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.ShowSingleApprover = true
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		approver, covered := testApprovers.SingleApproverCovered()
+		if approver != test.expectedApprover || covered != test.expectedCovered {
+			t.Errorf("Failed for test %v.  Expected %v (%v). Found %v (%v)", test.testName, test.expectedApprover, test.expectedCovered, approver, covered)
+		}
+
+		message := GetMessage(testApprovers, "org", "project", "")
+		if message == nil {
+			t.Fatalf("Failed for test %v.  GetMessage() failed", test.testName)
+		}
+		if shown := strings.Contains(*message, "(covers all files)"); shown != test.expectedCovered {
+			t.Errorf("Failed for test %v.  Expected the single approver shown: %v. Found %v", test.testName, test.expectedCovered, *message)
+		}
+		if test.expectedCovered && !strings.Contains(*message, "Approved by @"+test.expectedApprover+" (covers all files)") {
+			t.Errorf("Failed for test %v.  Expected %v in the message: %v", test.testName, test.expectedApprover, *message)
+		}
+	}
+}
+
+func TestApprovalStateDiff(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Alice"),
+		"b": sets.NewString("Bill"),
+	}
+	tests := []struct {
+		testName                 string
+		oldApprovers             []string
+		newApprovers             []string
+		expectedAddedApprovers   []string
+		expectedRemovedApprovers []string
+		expectedAddedCCs         []string
+		expectedRemovedCCs       []string
+		expectedChanged          bool
+	}{
+		{
+			testName:                 "Identical states",
+			oldApprovers:             []string{"Bill"},
+			newApprovers:             []string{"Bill"},
+			expectedAddedApprovers:   []string{},
+			expectedRemovedApprovers: []string{},
+			expectedAddedCCs:         []string{},
+			expectedRemovedCCs:       []string{},
+			expectedChanged:          false,
+		},
+		{
+			testName:                 "Added approver",
+			oldApprovers:             []string{"Bill"},
+			newApprovers:             []string{"Alice", "Bill"},
+			expectedAddedApprovers:   []string{"Alice"},
+			expectedRemovedApprovers: []string{},
+			expectedAddedCCs:         []string{},
+			expectedRemovedCCs:       []string{"Alice"},
+			expectedChanged:          true,
+		},
+		{
+			testName:                 "Removed approver",
+			oldApprovers:             []string{"Alice", "Bill"},
+			newApprovers:             []string{"Alice"},
+			expectedAddedApprovers:   []string{},
+			expectedRemovedApprovers: []string{"Bill"},
+			expectedAddedCCs:         []string{"Bill"},
+			expectedRemovedCCs:       []string{},
+			expectedChanged:          true,
+		},
+	}
+
+	for _, test := range tests {
+		owners := Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+		oldApprovers, newApprovers := NewApprovers(owners), NewApprovers(owners)
+		for _, approver := range test.oldApprovers {
+			oldApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		for _, approver := range test.newApprovers {
+			newApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+
+		addedApprovers, removedApprovers, addedCCs, removedCCs, changed := ApprovalStateDiff(oldApprovers, newApprovers)
+		if !reflect.DeepEqual(test.expectedAddedApprovers, addedApprovers) || !reflect.DeepEqual(test.expectedRemovedApprovers, removedApprovers) {
+			t.Errorf("Failed for test %v.  Expected added/removed approvers: %v/%v. Found %v/%v", test.testName, test.expectedAddedApprovers, test.expectedRemovedApprovers, addedApprovers, removedApprovers)
+		}
+		if !reflect.DeepEqual(test.expectedAddedCCs, addedCCs) || !reflect.DeepEqual(test.expectedRemovedCCs, removedCCs) {
+			t.Errorf("Failed for test %v.  Expected added/removed CCs: %v/%v. Found %v/%v", test.testName, test.expectedAddedCCs, test.expectedRemovedCCs, addedCCs, removedCCs)
+		}
+		if changed != test.expectedChanged {
+			t.Errorf("Failed for test %v.  Expected changed: %v. Found %v", test.testName, test.expectedChanged, changed)
+		}
+	}
+}
+
+func TestGetApprovalState(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.AddApprover("Bill", "REFERENCE", "")
+
+	state := GetApprovalState(ap, "org", "project", "")
+	if state.Approved {
+		t.Errorf("Expected the PR not to be approved")
+	}
+	if expected := []Approval{{Login: "Bill", How: "Approved", Reference: "REFERENCE"}}; !reflect.DeepEqual(state.Approvals, expected) {
+		t.Errorf("Expected approvals %v. Found %v", expected, state.Approvals)
+	}
+	if expected := []string{"Alice"}; !reflect.DeepEqual(state.SuggestedCCs, expected) {
+		t.Errorf("Expected suggested CCs %v. Found %v", expected, state.SuggestedCCs)
+	}
+	if state.SuggestedReviewers != nil {
+		t.Errorf("Expected no suggested reviewers. Found %v", state.SuggestedReviewers)
+	}
+
+	message := GetMessage(ap, "org", "project", "")
+	if message == nil {
+		t.Fatal("GetMessage() failed")
+	}
+	if !strings.Contains(*message, "**NOT APPROVED**") {
+		t.Errorf("Expected the message to be not approved: %v", *message)
+	}
+	for _, approval := range state.Approvals {
+		if !strings.Contains(*message, approval.String()) {
+			t.Errorf("Expected approval %v in the message: %v", approval, *message)
+		}
+	}
+	for _, cc := range state.SuggestedCCs {
+		if !strings.Contains(*message, "**"+cc+"**") {
+			t.Errorf("Expected suggested approver %v in the message: %v", cc, *message)
+		}
+	}
+	for _, file := range state.Files {
+		if !strings.Contains(*message, file.String()) {
+			t.Errorf("Expected file %v in the message: %v", file, *message)
+		}
+	}
+}
+
+func TestGetGubernatorMetadata(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+		},
+	)
+	ap.AddApprover("Bill", "REFERENCE", "")
+
+	comments := strings.Split(getGubernatorMetadata(ap, []string{"Alice"}), "\n")
+	if len(comments) != 3 || comments[0] != "" {
+		t.Fatalf("getGubernatorMetadata() = %q, expected a META and a SUMMARY comment", comments)
+	}
+	const prefix, summaryPrefix, suffix = "<!-- META=", "<!-- SUMMARY=", " -->"
+	if !strings.HasPrefix(comments[1], prefix) || !strings.HasSuffix(comments[1], suffix) {
+		t.Fatalf("getGubernatorMetadata() = %q, not a META comment", comments[1])
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(comments[1], prefix), suffix)
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		t.Fatalf("Failed to unmarshal %q: %v", body, err)
+	}
+	for _, key := range []string{"approvers", "files", "unapproved", "version"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("Expected key %q in metadata %q", key, body)
+		}
+	}
+
+	got := gubernatorMetadata{}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("Failed to unmarshal %q: %v", body, err)
+	}
+	want := gubernatorMetadata{
+		Approvers:  []string{"Alice"},
+		Files:      map[string][]string{"a": {}, "b": {"Bill"}},
+		Unapproved: []string{"a"},
+		Version:    2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getGubernatorMetadata() = %+v, want = %+v", got, want)
+	}
+
+	if !strings.HasPrefix(comments[2], summaryPrefix) || !strings.HasSuffix(comments[2], suffix) {
+		t.Fatalf("getGubernatorMetadata() = %q, not a SUMMARY comment", comments[2])
+	}
+	summaryBody := strings.TrimSuffix(strings.TrimPrefix(comments[2], summaryPrefix), suffix)
+	gotSummary := gubernatorSummary{}
+	if err := json.Unmarshal([]byte(summaryBody), &gotSummary); err != nil {
+		t.Fatalf("Failed to unmarshal %q: %v", summaryBody, err)
+	}
+	wantSummary := gubernatorSummary{Approved: false, ApprovedFiles: 1, TotalFiles: 2, Suggested: 1}
+	if gotSummary != wantSummary {
+		t.Errorf("getGubernatorMetadata() summary = %+v, want = %+v", gotSummary, wantSummary)
+	}
+}
+
+func TestGetCCsExcludedApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Author"),
+		"b": sets.NewString("Bill"),
+	}
+	testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	testApprovers.ExcludedApprovers = sets.NewString("author")
+	testApprovers.AddAssignees("Author")
+
+	if shuffled := testApprovers.suggestionOwners().GetShuffledApprovers(); !reflect.DeepEqual(shuffled, []string{"Bill"}) {
+		t.Errorf("Expected the author not to be a candidate. Found %v", shuffled)
+	}
+	if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, []string{"Bill"}) {
+		t.Errorf("Expected the author not to be suggested. Found %v", calculated)
+	}
+	if expected, calculated := sets.NewString("a", "b"), testApprovers.UnapprovedFiles(); !expected.Equal(calculated) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, calculated)
+	}
+}
+
+func TestGetCCsUnavailableApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	testApprovers.AvailableFunc = func(login string) bool { return login != "Art" }
+
+	if shuffled := testApprovers.suggestionOwners().GetShuffledApprovers(); !reflect.DeepEqual(shuffled, []string{"Bill"}) {
+		t.Errorf("Expected the unavailable approver not to be a candidate. Found %v", shuffled)
+	}
+	if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, []string{"Bill"}) {
+		t.Errorf("Expected the unavailable approver not to be suggested. Found %v", calculated)
+	}
+	if expected, calculated := sets.NewString("a", "b"), testApprovers.UnapprovedFiles(); !expected.Equal(calculated) {
+		t.Errorf("Expected unapproved files: %v. Found %v", expected, calculated)
+	}
+
+	testApprovers.AddApprover("Art", "REFERENCE", "")
+	if expected, calculated := sets.NewString("b"), testApprovers.UnapprovedFiles(); !expected.Equal(calculated) {
+		t.Errorf("Expected the unavailable approver to approve a. Unapproved files: %v. Found %v", expected, calculated)
+	}
+}
+
+func TestGetCCsBackupApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Barbara"),
+	}
+	tests := []struct {
+		testName    string
+		filenames   []string
+		unavailable string
+		expectedCCs []string
+	}{
+		{
+			testName:    "Primary Covers The File",
+			filenames:   []string{"a/test.go"},
+			expectedCCs: []string{"Art"},
+		},
+		{
+			testName:    "Only A Backup Covers The File",
+			filenames:   []string{"b/test.go"},
+			expectedCCs: []string{"Barbara"},
+		},
+		{
+			testName:    "Primary Unavailable",
+			filenames:   []string{"a/test.go"},
+			unavailable: "Art",
+			expectedCCs: []string{"Anne"},
+		},
+	}
+
+	for _, test := range tests {
+		repo := createFakeRepo(FakeRepoMap)
+		repo.BackupMap = map[string]sets.String{
+			"a": sets.NewString("Anne"),
+			"b": sets.NewString("Barbara"),
+		}
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: repo, seed: TEST_SEED})
+		if test.unavailable != "" {
+			unavailable := test.unavailable
+			testApprovers.AvailableFunc = func(login string) bool { return login != unavailable }
+		}
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(calculated, test.expectedCCs) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestLatestApprovalTime(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	first := time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC)
+	latest := first.Add(time.Hour)
+
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	if _, ok := ap.LatestApprovalTime(); ok {
+		t.Errorf("Expected no latest approval time without approvals")
+	}
+	ap.AddApprover("Alice", "REFERENCE", "")
+	if _, ok := ap.LatestApprovalTime(); ok {
+		t.Errorf("Expected no latest approval time without approval times")
+	}
+
+	ap.AddLGTMerAt("Bill", "REFERENCE", "", latest)
+	ap.AddApproverAt("Art", "REFERENCE", "", first)
+	if calculated, ok := ap.LatestApprovalTime(); !ok || !calculated.Equal(latest) {
+		t.Errorf("Expected latest approval time %v. Found %v (%v)", latest, calculated, ok)
+	}
+
+	ap.RemoveApprover("Bill")
+	if calculated, ok := ap.LatestApprovalTime(); !ok || !calculated.Equal(first) {
+		t.Errorf("Expected latest approval time %v. Found %v (%v)", first, calculated, ok)
+	}
+}
+
+func TestGetCCsActiveFunc(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":    sets.NewString("Alice", "Bob"),
+		"a":   sets.NewString("Art"),
+		"b":   sets.NewString("Bill", "Ben"),
+		"b/c": sets.NewString("Carl"),
+	}
+	tests := []struct {
+		testName    string
+		filenames   []string
+		inactive    sets.String
+		expectedCCs []string
+	}{
+		{
+			testName:    "All leaf approvers active",
+			filenames:   []string{"a/test.go"},
+			inactive:    sets.NewString(),
+			expectedCCs: []string{"Art"},
+		},
+		{
+			testName:    "All leaf approvers inactive",
+			filenames:   []string{"a/test.go"},
+			inactive:    sets.NewString("Art", "Bob"),
+			expectedCCs: []string{"Alice"},
+		},
+		{
+			testName:    "Inactive parent approvers aren't suggested",
+			filenames:   []string{"a/test.go"},
+			inactive:    sets.NewString("Art", "Alice"),
+			expectedCCs: []string{"Bob"},
+		},
+		{
+			testName:    "Parent approver covering several files",
+			filenames:   []string{"a/test.go", "b/c/test.go"},
+			inactive:    sets.NewString("Art", "Carl", "Alice", "Ben"),
+			expectedCCs: []string{"Bob"},
+		},
+		{
+			testName:    "Only escalate files with inactive leaf approvers",
+			filenames:   []string{"a/test.go", "b/c/test.go"},
+			inactive:    sets.NewString("Art", "Alice"),
+			expectedCCs: []string{"Bob", "Carl"},
+		},
+		{
+			testName:    "Everybody inactive",
+			filenames:   []string{"a/test.go"},
+			inactive:    sets.NewString("Art", "Alice", "Bob"),
+			expectedCCs: []string{"Art"},
+		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.ActiveFunc = func(login string) bool { return !test.inactive.Has(login) }
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(test.expectedCCs, calculated) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestGetCCsPreferAssignees(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	tests := []struct {
+		testName    string
+		assignees   []string
+		expectedCCs []string
+	}{
+		{
+			testName:    "No assignees",
+			assignees:   []string{},
+			expectedCCs: []string{"Art", "Bill"},
 		},
 		{
-			testName:          "Single Root File PR No One Approved",
-			filenames:         []string{"kubernetes.go"},
-			testSeed:          0,
-			currentlyApproved: sets.NewString(),
-			isApproved:        false,
+			testName:    "Assignees cover the PR",
+			assignees:   []string{"Art", "Bill"},
+			expectedCCs: []string{},
 		},
 		{
-			testName:          "Combo and Other; Neither Approved",
-			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
-			testSeed:          0,
-			currentlyApproved: sets.NewString(),
-			isApproved:        false,
+			testName:    "Root assignee is not a leaf approver",
+			assignees:   []string{"Alice"},
+			expectedCCs: []string{"Art", "Bill"},
 		},
 		{
-			testName:          "Combo and Other; Both Approved",
-			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
-			testSeed:          0,
-			currentlyApproved: edcApprovers.Intersection(dApprovers),
-			isApproved:        true,
+			testName:    "Assignees partially cover the PR",
+			assignees:   []string{"Art", "Carl"},
+			expectedCCs: []string{"Bill"},
 		},
+	}
+
+	for _, test := range tests {
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.PreferAssignees = true
+		testApprovers.AddAssignees(test.assignees...)
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(test.expectedCCs, calculated) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
+		}
+	}
+}
+
+func TestGetCCsWithReasons(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Anne"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl", "Zed"),
+		"d": sets.NewString("Dan", "Zed"),
+	}
+	testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	testApprovers.AddApprover("Bill", "REFERENCE", "")
+
+	expected := map[string]sets.String{
+		"Anne": sets.NewString("a"),
+		"Zed":  sets.NewString("c", "d"),
+	}
+	calculated := testApprovers.GetCCsWithReasons()
+	if !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected reasons: %v. Found %v", expected, calculated)
+	}
+	covered := sets.NewString()
+	for _, files := range calculated {
+		covered = covered.Union(files)
+	}
+	if unapproved := testApprovers.UnapprovedFiles(); !unapproved.Equal(covered) {
+		t.Errorf("Expected the suggestions to cover %v. Found %v", unapproved, covered)
+	}
+}
+
+func TestGetCCsMaxSuggested(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Anne"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl", "Zed"),
+		"d": sets.NewString("Dan", "Zed"),
+		"e": sets.NewString("Eve", "Zed"),
+	}
+	tests := []struct {
+		testName     string
+		maxSuggested int
+		approvers    []string
+		expectedCCs  []string
+	}{
 		{
-			testName:          "A, B, C; Nothing Approved",
-			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
-			testSeed:          0,
-			currentlyApproved: sets.NewString(),
-			isApproved:        false,
+			testName:     "No Limit",
+			maxSuggested: 0,
+			expectedCCs:  []string{"Anne", "Bill", "Zed"},
 		},
 		{
-			testName:          "A, B, C; Partially Approved",
-			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
-			testSeed:          0,
-			currentlyApproved: aApprovers.Union(bApprovers),
-			isApproved:        false,
+			testName:     "Cap of 2 Keeps the Most Covering",
+			maxSuggested: 2,
+			expectedCCs:  []string{"Anne", "Zed"},
 		},
 		{
-			testName:          "A, B, C; Approved At the Root",
-			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
-			testSeed:          0,
-			currentlyApproved: rootApprovers,
-			isApproved:        true,
+			testName:     "Cap Not Reached",
+			maxSuggested: 2,
+			approvers:    []string{"Zed"},
+			expectedCCs:  []string{"Anne", "Bill"},
 		},
 		{
-			testName:          "A, B, C; Approved At the Leaves",
-			filenames:         []string{"a/test.go", "b/test.go", "c/test"},
-			testSeed:          0,
-			currentlyApproved: sets.NewString("Anne", "Ben", "Carol"),
-			isApproved:        true,
+			testName:     "Cap of 1",
+			maxSuggested: 1,
+			expectedCCs:  []string{"Zed"},
 		},
 	}
 
 	for _, test := range tests {
-		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: test.testSeed})
-		for approver := range test.currentlyApproved {
-			testApprovers.AddApprover(approver, "REFERENCE")
+		testApprovers := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go", "e/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		testApprovers.MaxSuggested = test.maxSuggested
+		for _, approver := range test.approvers {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
 		}
-		calculated := testApprovers.IsApproved()
-		if test.isApproved != calculated {
-			t.Errorf("Failed for test %v.  Expected Approval Status: %v. Found %v", test.testName, test.isApproved, calculated)
+		if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(test.expectedCCs, calculated) {
+			t.Errorf("Failed for test %v.  Expected CCs: %v. Found %v", test.testName, test.expectedCCs, calculated)
 		}
 	}
 }
 
-func TestGetFilesApprovers(t *testing.T) {
+func TestGetCCsCached(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice", "Bob"),
+		"a": sets.NewString("Art", "Anne"),
+		"b": sets.NewString("Bill", "Ben"),
+	}
+	filenames := []string{"a/test.go", "b/test.go"}
+	for seed := int64(0); seed < 10; seed++ {
+		uncached := NewApprovers(Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: seed})
+		cached := NewApprovers(NewOwners(filenames, createFakeRepo(FakeRepoMap), seed))
+		for i := 0; i < 2; i++ {
+			if expected, calculated := uncached.GetCCs(), cached.GetCCs(); !reflect.DeepEqual(expected, calculated) {
+				t.Errorf("Failed for seed %v.  Expected CCs: %v. Found %v", seed, expected, calculated)
+			}
+		}
+	}
+
+	// Owners of different files don't share their cache.
+	aOwners := NewOwners([]string{"a/test.go"}, createFakeRepo(FakeRepoMap), TEST_SEED)
+	bOwners := NewOwners([]string{"b/test.go"}, createFakeRepo(FakeRepoMap), TEST_SEED)
+	if aSet, bSet := aOwners.GetOwnersSet(), bOwners.GetOwnersSet(); !aSet.Equal(sets.NewString("a")) || !bSet.Equal(sets.NewString("b")) {
+		t.Errorf("Expected owners sets [a] and [b], found %v and %v", aSet, bSet)
+	}
+}
+
+func TestGetCCsSingleOwnersFile(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":    sets.NewString("Alice", "Bob"),
+		"a":   sets.NewString("Art", "Anne", "Amy"),
+		"a/c": sets.NewString("Carl"),
+		"b":   sets.NewString(),
+	}
 	tests := []struct {
-		testName       string
-		filenames      []string
-		approvers      []string
-		owners         map[string]sets.String
-		expectedStatus map[string]sets.String
+		testName  string
+		filenames []string
+		approvers []string
+		assignees []string
+		excluded  []string
+		// caseSensitive sets CaseSensitive.
+		caseSensitive bool
+		// scoped approve a/test.go only, cancelled cancel their
+		// approval of a.
+		scoped    []string
+		cancelled []string
+	}{
+		{testName: "No approvers", filenames: []string{"a/test.go"}},
+		{testName: "Several files in the directory", filenames: []string{"a/test.go", "a/other.go"}},
+		{testName: "Approved", filenames: []string{"a/test.go"}, approvers: []string{"Anne"}},
+		{testName: "Approved by root", filenames: []string{"a/test.go"}, approvers: []string{"Alice"}},
+		{testName: "Approved, with assignees", filenames: []string{"a/test.go"}, approvers: []string{"art"}, assignees: []string{"Amy", "Bob"}},
+		{testName: "Leaf assignee", filenames: []string{"a/test.go"}, assignees: []string{"Amy"}},
+		{testName: "Root assignee", filenames: []string{"a/test.go"}, assignees: []string{"Bob"}},
+		{testName: "Useless assignee", filenames: []string{"a/test.go"}, assignees: []string{"Carl"}},
+		{testName: "Excluded assignee", filenames: []string{"a/test.go"}, assignees: []string{"Bob"}, excluded: []string{"bob"}},
+		{testName: "All leaf approvers excluded", filenames: []string{"a/c/test.go"}, assignees: []string{"Alice", "Carl"}, excluded: []string{"Carl"}},
+		{testName: "No leaf approvers", filenames: []string{"b/test.go"}, assignees: []string{"Bob"}},
+		{testName: "Case mismatch, case-sensitive", filenames: []string{"a/test.go"}, approvers: []string{"art"}, caseSensitive: true},
+		{testName: "Same case, case-sensitive", filenames: []string{"a/test.go"}, approvers: []string{"Art"}, caseSensitive: true},
+		{testName: "Case mismatched assignee, case-sensitive", filenames: []string{"a/test.go"}, assignees: []string{"amy"}, caseSensitive: true},
+		{testName: "Scoped approval", filenames: []string{"a/test.go"}, scoped: []string{"Anne"}},
+		{testName: "Scoped approval elsewhere", filenames: []string{"a/c/test.go"}, scoped: []string{"Alice"}},
+		{testName: "Cancelled approval", filenames: []string{"a/test.go"}, approvers: []string{"Alice"}, cancelled: []string{"Alice"}},
+		{testName: "Cancelled approval, with assignees", filenames: []string{"a/test.go"}, approvers: []string{"Alice"}, cancelled: []string{"Alice"}, assignees: []string{"Amy"}},
+	}
+
+	for _, test := range tests {
+		for seed := int64(0); seed < 10; seed++ {
+			testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: seed})
+			testApprovers.ExcludedApprovers = sets.NewString(test.excluded...)
+			testApprovers.AddAssignees(test.assignees...)
+			testApprovers.CaseSensitive = test.caseSensitive
+			for _, approver := range test.approvers {
+				testApprovers.AddApprover(approver, "REFERENCE", "")
+			}
+			for _, approver := range test.scoped {
+				testApprovers.AddApproverForFile(approver, "a/test.go", "REFERENCE")
+			}
+			for _, approver := range test.cancelled {
+				testApprovers.RemoveApproverForPath(approver, "a")
+			}
+
+			suggested, keepAssignees := testApprovers.getCoveringCCs(testApprovers.suggestionOwners())
+			expected := suggested.Union(keepAssignees).List()
+			if calculated := testApprovers.GetCCs(); !reflect.DeepEqual(expected, calculated) {
+				t.Errorf("Failed for test %v with seed %v.  Expected CCs: %v. Found %v", test.testName, seed, expected, calculated)
+			}
+		}
+	}
+}
+
+func TestMinimalAssignRequest(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	tests := []struct {
+		testName  string
+		approvers []string
+		excluded  []string
+		expected  []string
 	}{
 		{
-			testName:       "Empty PR",
-			filenames:      []string{},
-			approvers:      []string{},
-			owners:         map[string]sets.String{},
-			expectedStatus: map[string]sets.String{},
-		},
-		{
-			testName:       "No approvers",
-			filenames:      []string{"a/a", "c"},
-			approvers:      []string{},
-			owners:         map[string]sets.String{"": sets.NewString("RootOwner")},
-			expectedStatus: map[string]sets.String{"": sets.String{}},
+			testName: "Root Approver Covers Everything",
+			expected: []string{"Alice"},
 		},
 		{
-			testName: "Approvers approves some",
-			filenames: []string{
-				"a/a",
-				"c/c",
-			},
-			approvers: []string{"CApprover"},
-			owners: map[string]sets.String{
-				"a": sets.NewString("AApprover"),
-				"c": sets.NewString("CApprover"),
-			},
-			expectedStatus: map[string]sets.String{
-				"a": sets.String{},
-				"c": sets.NewString("CApprover"),
-			},
+			testName:  "Approved Files Are Ignored",
+			approvers: []string{"Art", "Bill"},
+			expected:  []string{"Carl"},
 		},
 		{
-			testName: "Multiple approvers",
-			filenames: []string{
-				"a/a",
-				"c/c",
-			},
-			approvers: []string{"RootApprover", "CApprover"},
-			owners: map[string]sets.String{
-				"":  sets.NewString("RootApprover"),
-				"a": sets.NewString("AApprover"),
-				"c": sets.NewString("CApprover"),
-			},
-			expectedStatus: map[string]sets.String{
-				"a": sets.NewString("RootApprover"),
-				"c": sets.NewString("RootApprover", "CApprover"),
-			},
+			testName: "Excluded Root Approver",
+			excluded: []string{"Alice"},
+			expected: []string{"Art", "Bill", "Carl"},
 		},
 		{
-			testName:       "Case-insensitive approvers",
-			filenames:      []string{"file"},
-			approvers:      []string{"RootApprover"},
-			owners:         map[string]sets.String{"": sets.NewString("rOOtaPProver")},
-			expectedStatus: map[string]sets.String{"": sets.NewString("RootApprover")},
+			testName:  "Approved PR",
+			approvers: []string{"Alice"},
+			expected:  []string{},
 		},
 	}
 
 	for _, test := range tests {
-		testApprovers := NewApprovers(Owners{filenames: test.filenames, repo: createFakeRepo(test.owners)})
+		ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+		ap.ExcludedApprovers = sets.NewString(test.excluded...)
 		for _, approver := range test.approvers {
-			testApprovers.AddApprover(approver, "REFERENCE")
+			ap.AddApprover(approver, "REFERENCE", "")
 		}
-		calculated := testApprovers.GetFilesApprovers()
-		if !reflect.DeepEqual(test.expectedStatus, calculated) {
-			t.Errorf("Failed for test %v.  Expected approval status: %v. Found %v", test.testName, test.expectedStatus, calculated)
+		if calculated := ap.MinimalAssignRequest(); !reflect.DeepEqual(test.expected, calculated) {
+			t.Errorf("Failed for test %v.  Expected assign request: %v. Found %v", test.testName, test.expected, calculated)
 		}
 	}
+
+	// GetCCs only suggests the closest approvers.
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	if minimal, ccs := ap.MinimalAssignRequest(), ap.GetCCs(); len(minimal) >= len(ccs) {
+		t.Errorf("Expected the assign request %v to be smaller than the CCs %v", minimal, ccs)
+	}
 }
 
-func TestGetMessage(t *testing.T) {
-	ap := NewApprovers(
-		Owners{
-			filenames: []string{"a/a.go", "b/b.go"},
-			repo: createFakeRepo(map[string]sets.String{
-				"a": sets.NewString("Alice"),
-				"b": sets.NewString("Bill"),
-			}),
-		},
-	)
-	ap.AddApprover("Bill", "REFERENCE")
+func TestGetCCsExplain(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
+	ap.AddApprover("Art", "REFERENCE", "")
+	ap.AddAssignees("Bill", "Zach")
 
-	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+	explanation := ap.GetCCsExplain()
+	if expected := ap.GetCCs(); !reflect.DeepEqual(expected, explanation.CCs) {
+		t.Errorf("Expected CCs %v. Found %v", expected, explanation.CCs)
+	}
+	if union := explanation.Suggested.Union(explanation.KeptAssignees).List(); !reflect.DeepEqual(union, explanation.CCs) {
+		t.Errorf("Expected the CCs to be the union of %v and %v. Found %v", explanation.Suggested, explanation.KeptAssignees, explanation.CCs)
+	}
+	if expected := sets.NewString("Carl"); !expected.Equal(explanation.Suggested) {
+		t.Errorf("Expected suggested %v. Found %v", expected, explanation.Suggested)
+	}
+	if expected := sets.NewString("Bill"); !expected.Equal(explanation.KeptAssignees) {
+		t.Errorf("Expected kept assignees %v. Found %v", expected, explanation.KeptAssignees)
+	}
+	if pool := sets.NewString(explanation.RandomizedPool...); !pool.Equal(sets.NewString("Art", "Bill", "Carl")) {
+		t.Errorf("Expected the pool to be the leaf approvers. Found %v", explanation.RandomizedPool)
+	}
+}
 
-This pull-request has been approved by: *<a href="REFERENCE" title="Approved">Bill</a>*
-We suggest the following additional approver: **Alice**
+func TestGetCCsWithStats(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
 
-Assign the PR to them by writing ` + "`/assign @Alice`" + ` in a comment when ready.
+	ccs, stats := ap.GetCCsWithStats()
+	if expected := ap.GetCCs(); !reflect.DeepEqual(expected, ccs) {
+		t.Errorf("Expected CCs %v. Found %v", expected, ccs)
+	}
+	if stats.Iterations < 3 {
+		t.Errorf("Expected at least an iteration per OWNERS file. Found %v", stats.Iterations)
+	}
+	if stats.PoolSize != 3 {
+		t.Errorf("Expected a pool of 3 candidates. Found %v", stats.PoolSize)
+	}
+	if stats.Elapsed <= 0 {
+		t.Errorf("Expected the elapsed time to be measured. Found %v", stats.Elapsed)
+	}
+	if ap.stats != nil {
+		t.Errorf("Expected GetCCsWithStats not to instrument the Approvers")
+	}
+}
 
-<details open>
-Needs approval from an approver in each of these OWNERS Files:
+func TestGetCCsMemoized(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	ap := NewApprovers(Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED})
 
-- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**
-- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]
+	first := ap.GetCCs()
+	second := ap.GetCCs()
+	if len(first) == 0 || &first[0] != &second[0] {
+		t.Errorf("Expected consecutive calls to return the same slice. Found %v and %v", first, second)
+	}
 
-You can indicate your approval by writing ` + "`/approve`" + ` in a comment
-You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
-</details>
-<!-- META={"approvers":["Alice"]} -->`
-	if got := GetMessage(ap, "org", "project"); got == nil {
-		t.Error("GetMessage() failed")
-	} else if *got != want {
-		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	ap.AddApprover("Art", "REFERENCE", "")
+	if calculated, expected := ap.GetCCs(), []string{"Bill"}; !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected CCs %v after approving. Found %v", expected, calculated)
+	}
+	ap.AddAssignees("Alice")
+	if calculated, expected := ap.GetCCs(), []string{"Alice"}; !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected CCs %v after assigning. Found %v", expected, calculated)
 	}
 }
 
-func TestGetMessageAllApproved(t *testing.T) {
-	ap := NewApprovers(
-		Owners{
-			filenames: []string{"a/a.go", "b/b.go"},
-			repo: createFakeRepo(map[string]sets.String{
-				"a": sets.NewString("Alice"),
-				"b": sets.NewString("Bill"),
-			}),
-		},
-	)
-	ap.AddApprover("Alice", "REFERENCE")
-	ap.AddLGTMer("Bill", "REFERENCE")
+func benchmarkGetCCs(b *testing.B, newOwners func(filenames []string, repo RepoInterface) Owners) {
+	FakeRepoMap := map[string]sets.String{"": sets.NewString("Alice", "Bob")}
+	filenames := []string{}
+	for i := 0; i < 50; i++ {
+		dir := fmt.Sprintf("dir%d", i)
+		FakeRepoMap[dir] = sets.NewString(fmt.Sprintf("Approver%d", i), fmt.Sprintf("Approver%d", i+1))
+		for j := 0; j < 10; j++ {
+			filenames = append(filenames, fmt.Sprintf("%s/file%d.go", dir, j))
+		}
+	}
+	repo := createFakeRepo(FakeRepoMap)
 
-	want := `[APPROVALNOTIFIER] This PR is **APPROVED**
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewApprovers(newOwners(filenames, repo)).GetCCs()
+	}
+}
 
-This pull-request has been approved by: *<a href="REFERENCE" title="Approved">Alice</a>*, *<a href="REFERENCE" title="LGTM">Bill</a>*
+func BenchmarkGetCCsUncached(b *testing.B) {
+	benchmarkGetCCs(b, func(filenames []string, repo RepoInterface) Owners {
+		return Owners{filenames: filenames, repo: repo, seed: TEST_SEED}
+	})
+}
 
-<details >
-Needs approval from an approver in each of these OWNERS Files:
+func BenchmarkGetCCsCached(b *testing.B) {
+	benchmarkGetCCs(b, func(filenames []string, repo RepoInterface) Owners {
+		return NewOwners(filenames, repo, TEST_SEED)
+	})
+}
 
-- ~~[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)~~ [Alice]
-- ~~[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)~~ [Bill]
+func benchmarkGetCCsSingleOwnersFile(b *testing.B, getCCs func(ap Approvers) (sets.String, sets.String)) {
+	FakeRepoMap := map[string]sets.String{
+		"":    sets.NewString("Alice", "Bob"),
+		"dir": sets.NewString(),
+	}
+	for i := 0; i < 50; i++ {
+		FakeRepoMap["dir"].Insert(fmt.Sprintf("Approver%d", i))
+	}
+	filenames := []string{}
+	for i := 0; i < 10; i++ {
+		filenames = append(filenames, fmt.Sprintf("dir/file%d.go", i))
+	}
+	repo := createFakeRepo(FakeRepoMap)
 
-You can indicate your approval by writing ` + "`/approve`" + ` in a comment
-You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
-</details>
-<!-- META={"approvers":[]} -->`
-	if got := GetMessage(ap, "org", "project"); got == nil {
-		t.Error("GetMessage() failed")
-	} else if *got != want {
-		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getCCs(NewApprovers(NewOwners(filenames, repo, TEST_SEED)))
 	}
 }
 
-func TestGetMessageNoneApproved(t *testing.T) {
-	ap := NewApprovers(
-		Owners{
-			filenames: []string{"a/a.go", "b/b.go"},
-			repo: createFakeRepo(map[string]sets.String{
-				"a": sets.NewString("Alice"),
-				"b": sets.NewString("Bill"),
-			}),
-		},
-	)
-
-	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+func benchmarkRepeatedGetCCs(b *testing.B, getCCs func(ap Approvers) []string) {
+	FakeRepoMap := map[string]sets.String{"": sets.NewString("Alice", "Bob")}
+	filenames := []string{}
+	for i := 0; i < 50; i++ {
+		dir := fmt.Sprintf("dir%d", i)
+		FakeRepoMap[dir] = sets.NewString(fmt.Sprintf("Approver%d", i), fmt.Sprintf("Approver%d", i+1))
+		filenames = append(filenames, fmt.Sprintf("%s/file.go", dir))
+	}
+	repo := createFakeRepo(FakeRepoMap)
 
-This pull-request has been approved by: 
-We suggest the following additional approvers: **Alice**, **Bill**
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ap := NewApprovers(NewOwners(filenames, repo, TEST_SEED))
+		// The message and the metadata both need the CCs.
+		getCCs(ap)
+		getCCs(ap)
+	}
+}
 
-Assign the PR to them by writing ` + "`/assign @Alice @Bill`" + ` in a comment when ready.
+func BenchmarkRepeatedGetCCsMemoized(b *testing.B) {
+	benchmarkRepeatedGetCCs(b, Approvers.GetCCs)
+}
 
-<details open>
-Needs approval from an approver in each of these OWNERS Files:
+func BenchmarkRepeatedGetCCsRecomputed(b *testing.B) {
+	benchmarkRepeatedGetCCs(b, Approvers.computeCCs)
+}
 
-- **[a/OWNERS](https://github.com/org/project/blob/master/a/OWNERS)**
-- **[b/OWNERS](https://github.com/org/project/blob/master/b/OWNERS)**
+func BenchmarkGetCCsSingleOwnersFileFastPath(b *testing.B) {
+	benchmarkGetCCsSingleOwnersFile(b, func(ap Approvers) (sets.String, sets.String) {
+		return ap.getCCs()
+	})
+}
 
-You can indicate your approval by writing ` + "`/approve`" + ` in a comment
-You can cancel your approval by writing ` + "`/approve cancel`" + ` in a comment
-</details>
-<!-- META={"approvers":["Alice","Bill"]} -->`
-	if got := GetMessage(ap, "org", "project"); got == nil {
-		t.Error("GetMessage() failed")
-	} else if *got != want {
-		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
-	}
+func BenchmarkGetCCsSingleOwnersFileGeneral(b *testing.B) {
+	benchmarkGetCCsSingleOwnersFile(b, func(ap Approvers) (sets.String, sets.String) {
+		return ap.getCoveringCCs(ap.suggestionOwners())
+	})
 }
@@ -0,0 +1,243 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/test-infra/mungegithub/features"
+)
+
+// MaxParallelLookups is the maximum number of OWNERS files whose people
+// are looked up concurrently, for repos doing I/O on each lookup.
+var MaxParallelLookups = 8
+
+// ContextRepoInterface is implemented by repos whose lookups can be
+// cancelled. Repos that don't implement it are still cancellable, but
+// the blocked lookup keeps running in the background.
+type ContextRepoInterface interface {
+	ApproversContext(ctx context.Context, path string) (sets.String, error)
+	LeafApproversContext(ctx context.Context, path string) (sets.String, error)
+	FindApproverOwnersForPathContext(ctx context.Context, path string) (string, error)
+}
+
+// withContext returns a cancellable view of repo.
+func withContext(repo RepoInterface) ContextRepoInterface {
+	if r, ok := repo.(ContextRepoInterface); ok {
+		return r
+	}
+	return contextRepo{repo}
+}
+
+// contextRepo makes the lookups of a RepoInterface cancellable by running
+// them in a goroutine.
+type contextRepo struct {
+	repo RepoInterface
+}
+
+func (r contextRepo) ApproversContext(ctx context.Context, path string) (sets.String, error) {
+	result, err := lookupContext(ctx, func() interface{} { return r.repo.Approvers(path) })
+	if err != nil {
+		return nil, err
+	}
+	return result.(sets.String), nil
+}
+
+func (r contextRepo) LeafApproversContext(ctx context.Context, path string) (sets.String, error) {
+	result, err := lookupContext(ctx, func() interface{} { return r.repo.LeafApprovers(path) })
+	if err != nil {
+		return nil, err
+	}
+	return result.(sets.String), nil
+}
+
+func (r contextRepo) FindApproverOwnersForPathContext(ctx context.Context, path string) (string, error) {
+	result, err := lookupContext(ctx, func() interface{} { return r.repo.FindApproverOwnersForPath(path) })
+	if err != nil {
+		return "", err
+	}
+	return result.(string), nil
+}
+
+// lookupContext returns the result of lookup, or the error of ctx if it is
+// done first. Lookups are run directly if ctx can't be cancelled.
+func lookupContext(ctx context.Context, lookup func() interface{}) (interface{}, error) {
+	if ctx.Done() == nil {
+		return lookup(), nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := make(chan interface{}, 1)
+	go func() { result <- lookup() }()
+	select {
+	case r := <-result:
+		return r, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *RepoAlias) ApproversContext(ctx context.Context, path string) (sets.String, error) {
+	approvers, err := withContext(r.repo).ApproversContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return r.expand(approvers), nil
+}
+
+func (r *RepoAlias) LeafApproversContext(ctx context.Context, path string) (sets.String, error) {
+	approvers, err := withContext(r.repo).LeafApproversContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return r.expand(approvers), nil
+}
+
+func (r *RepoAlias) FindApproverOwnersForPathContext(ctx context.Context, path string) (string, error) {
+	return withContext(r.repo).FindApproverOwnersForPathContext(ctx, path)
+}
+
+// Prefetch does the repo lookups of the PR's OWNERS files, aborting if ctx
+// is done first. Once it succeeded, these OWNERS files are no longer read
+// while evaluating the PR. Finding the OWNERS file of a path, and reading
+// OWNERS files covering only part of the PR, e.g. for approvals scoped to a
+// path, still use the repo. It's a no-op if o doesn't cache its lookups.
+func (o Owners) Prefetch(ctx context.Context) error {
+	if o.cache == nil {
+		return nil
+	}
+	ownersSet, err := o.getOwnersSetContext(ctx)
+	if err != nil {
+		return err
+	}
+	o.cache.ownersSetOnce.Do(func() { o.cache.ownersSet = ownersSet })
+	ownersSet = o.cache.ownersSet
+
+	approvers, err := o.getApproversContext(ctx, ownersSet)
+	if err != nil {
+		return err
+	}
+	o.cache.approversOnce.Do(func() { o.cache.approvers = approvers })
+
+	leafApprovers, err := o.getLeafApproversContext(ctx, ownersSet)
+	if err != nil {
+		return err
+	}
+	o.cache.leafApproversOnce.Do(func() { o.cache.leafApprovers = leafApprovers })
+
+	for _, fn := range ownersSet.List() {
+		fn := fn
+		details, err := lookupContext(ctx, func() interface{} { return lookupDetails(o.repo, fn) })
+		if err != nil {
+			return err
+		}
+		o.cache.setDetails(fn, details.(ownersDetails))
+	}
+
+	rootApprovers, rootRequired, err := o.getRootCoverContext(ctx, ownersSet)
+	if err != nil {
+		return err
+	}
+	o.cache.rootCoverOnce.Do(func() { o.cache.rootApprovers, o.cache.rootRequired = rootApprovers, rootRequired })
+	return nil
+}
+
+// getRootCoverContext is Owners.rootCover for the given OWNERS files,
+// aborting if ctx is done before the root approvers are looked up.
+func (o Owners) getRootCoverContext(ctx context.Context, ownersSet sets.String) (approvers sets.String, required bool, err error) {
+	for fn := range ownersSet {
+		dir, _ := features.SplitOwnersKey(fn)
+		if o.requiredApprovals(fn) > 1 || !inheritsFrom("", dir, o.repo.IsNoParentOwners) {
+			return sets.NewString(), false, nil
+		}
+		if o.requiredApprovers(fn).Len() != 0 {
+			required = true
+		}
+	}
+	approvers, err = withContext(o.repo).ApproversContext(ctx, "")
+	if err != nil {
+		return nil, false, err
+	}
+	return approvers, required, nil
+}
+
+// Prefetch does the repo lookups needed to evaluate the PR, see
+// Owners.Prefetch.
+func (ap Approvers) Prefetch(ctx context.Context) error {
+	return ap.owners.Prefetch(ctx)
+}
+
+// lookupEach maps each of the OWNERS files to the people returned by
+// lookup, running up to MaxParallelLookups lookups at a time. The first
+// error is returned.
+func lookupEach(ctx context.Context, ownersFiles sets.String, lookup func(ctx context.Context, path string) (sets.String, error)) (map[string]sets.String, error) {
+	results := map[string]sets.String{}
+	workers := MaxParallelLookups
+	if workers > ownersFiles.Len() {
+		workers = ownersFiles.Len()
+	}
+	if workers <= 1 {
+		for _, fn := range ownersFiles.List() {
+			people, err := lookup(ctx, fn)
+			if err != nil {
+				return nil, err
+			}
+			results[fn] = people
+		}
+		return results, nil
+	}
+
+	var (
+		lock     sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	files := make(chan string, ownersFiles.Len())
+	for _, fn := range ownersFiles.List() {
+		files <- fn
+	}
+	close(files)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fn := range files {
+				lock.Lock()
+				failed := firstErr != nil
+				lock.Unlock()
+				if failed {
+					return
+				}
+				people, err := lookup(ctx, fn)
+				lock.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				results[fn] = people
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// blockingRepo is a FakeRepo whose approvers lookups block until their
+// context is done.
+type blockingRepo struct {
+	FakeRepo
+}
+
+func (r blockingRepo) ApproversContext(ctx context.Context, path string) (sets.String, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r blockingRepo) LeafApproversContext(ctx context.Context, path string) (sets.String, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r blockingRepo) FindApproverOwnersForPathContext(ctx context.Context, path string) (string, error) {
+	return r.FindApproverOwnersForPath(path), nil
+}
+
+// hungRepo is a FakeRepo whose OWNERS lookups block until released,
+// ignoring any context.
+type hungRepo struct {
+	FakeRepo
+	release chan struct{}
+}
+
+func (r hungRepo) Approvers(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.Approvers(path)
+}
+
+func (r hungRepo) Labels(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.Labels(path)
+}
+
+func (r hungRepo) EmeritusApprovers(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.EmeritusApprovers(path)
+}
+
+func (r hungRepo) PrimaryApprovers(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.PrimaryApprovers(path)
+}
+
+func (r hungRepo) RequiredApprovals(path string) int {
+	<-r.release
+	return r.FakeRepo.RequiredApprovals(path)
+}
+
+func (r hungRepo) RequiredApprovers(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.RequiredApprovers(path)
+}
+
+// hungEmeritusRepo is a FakeRepo whose emeritus approvers lookups block
+// until released, ignoring any context.
+type hungEmeritusRepo struct {
+	FakeRepo
+	release chan struct{}
+}
+
+func (r hungEmeritusRepo) EmeritusApprovers(path string) sets.String {
+	<-r.release
+	return r.FakeRepo.EmeritusApprovers(path)
+}
+
+func TestPrefetch(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")})
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		testName string
+		repo     RepoInterface
+	}{
+		{
+			testName: "Context Aware Repo",
+			repo:     blockingRepo{fakeRepo},
+		},
+		{
+			testName: "Hung Repo",
+			repo:     hungRepo{fakeRepo, release},
+		},
+		{
+			testName: "Hung Emeritus Lookup",
+			repo:     hungEmeritusRepo{fakeRepo, release},
+		},
+	}
+
+	for _, test := range tests {
+		ap := NewApprovers(NewOwners([]string{"a/test.go"}, test.repo, TEST_SEED))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := ap.Prefetch(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Failed for test %v.  Expected %v. Found %v", test.testName, context.DeadlineExceeded, err)
+		}
+		cancel()
+	}
+}
+
+func TestPrefetchCaches(t *testing.T) {
+	ap := NewApprovers(NewOwners([]string{"a/test.go"}, createFakeRepo(map[string]sets.String{"a": sets.NewString("Alice")}), TEST_SEED))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := ap.Prefetch(ctx); err != nil {
+		t.Fatalf("Prefetch() failed: %v", err)
+	}
+
+	// The repo is no longer used once the lookups are cached.
+	ap.owners.repo = hungRepo{}
+	ap.AddApprover("Alice", "REFERENCE", "")
+	if !ap.IsApproved() {
+		t.Errorf("Expected the PR to be approved")
+	}
+	if labels := ap.owners.GetRequiredLabels(); labels.Len() != 0 {
+		t.Errorf("Expected no required labels. Found %v", labels)
+	}
+	if potential := ap.owners.GetAllPotentialApprovers(); !reflect.DeepEqual(potential, []string{"Alice"}) {
+		t.Errorf("Expected potential approvers %v. Found %v", []string{"Alice"}, potential)
+	}
+}
+
+// sleepingRepo is a FakeRepo whose approvers lookups take delay, like
+// lookups reading the OWNERS files.
+type sleepingRepo struct {
+	FakeRepo
+	delay time.Duration
+}
+
+func (r sleepingRepo) Approvers(path string) sets.String {
+	time.Sleep(r.delay)
+	return r.FakeRepo.Approvers(path)
+}
+
+func (r sleepingRepo) LeafApprovers(path string) sets.String {
+	time.Sleep(r.delay)
+	return r.FakeRepo.LeafApprovers(path)
+}
+
+func TestParallelLookups(t *testing.T) {
+	defer func(max int) { MaxParallelLookups = max }(MaxParallelLookups)
+
+	FakeRepoMap := map[string]sets.String{}
+	filenames := []string{}
+	for i := 0; i < 8; i++ {
+		dir := fmt.Sprintf("dir%d", i)
+		FakeRepoMap[dir] = sets.NewString(fmt.Sprintf("Approver%d", i))
+		filenames = append(filenames, dir+"/test.go")
+	}
+	delay := 20 * time.Millisecond
+	repo := sleepingRepo{createFakeRepo(FakeRepoMap), delay}
+	owners := Owners{filenames: filenames, repo: repo, seed: TEST_SEED}
+
+	MaxParallelLookups = 1
+	serialApprovers, serialLeafApprovers := owners.GetApprovers(), owners.GetLeafApprovers()
+
+	MaxParallelLookups = 8
+	start := time.Now()
+	approvers, leafApprovers := owners.GetApprovers(), owners.GetLeafApprovers()
+	// Serial lookups take 16 delays.
+	if elapsed := time.Since(start); elapsed >= 8*delay {
+		t.Errorf("Expected the lookups to run in parallel. Took %v", elapsed)
+	}
+	if !reflect.DeepEqual(serialApprovers, approvers) {
+		t.Errorf("Expected approvers %v. Found %v", serialApprovers, approvers)
+	}
+	if !reflect.DeepEqual(serialLeafApprovers, leafApprovers) {
+		t.Errorf("Expected leaf approvers %v. Found %v", serialLeafApprovers, leafApprovers)
+	}
+}
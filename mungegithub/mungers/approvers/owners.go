@@ -18,13 +18,19 @@ package approvers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"html"
 	"math/rand"
+	"net/url"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/golang/glog"
 	"k8s.io/kubernetes/pkg/util/sets"
@@ -33,19 +39,141 @@ import (
 )
 
 const (
-	ownersFileName           = "OWNERS"
+	defaultOwnersFileName    = "OWNERS"
+	defaultBranch            = "master"
+	defaultBaseURL           = "https://github.com"
 	ApprovalNotificationName = "ApprovalNotifier"
 )
 
+// MinimalApproversThreshold is the maximum number of OWNERS files for which
+// GetMinimalApprovers searches for an exact minimal set of approvers.
+var MinimalApproversThreshold = 15
+
+// MaxListedApprovers is the maximum number of approvers listed next to an
+// approved file in the message, the others are only counted.
+var MaxListedApprovers = 10
+
+// loadPenalty is the number of covered files that one unit of load is
+// worth when suggesting approvers with a load function.
+const loadPenalty = 0.1
+
 type RepoInterface interface {
 	Approvers(path string) sets.String
 	LeafApprovers(path string) sets.String
+	Reviewers(path string) sets.String
+	LeafReviewers(path string) sets.String
 	FindApproverOwnersForPath(path string) string
+	IsNoParentOwners(path string) bool
+	EmeritusApprovers(path string) sets.String
+	Labels(path string) sets.String
+	RequiredApprovals(path string) int
+	RequiredApprovers(path string) sets.String
+	// PrimaryApprovers are the approvers who aren't BackupApprovers.
+	PrimaryApprovers(path string) sets.String
+	BackupApprovers(path string) sets.String
+}
+
+// OwnersFileNamer is implemented by repos whose ownership files aren't
+// all named OWNERS, e.g. with legacy MAINTAINERS files. OwnersFileName
+// returns the name of the ownership file of an OWNERS key, or "" if
+// unknown.
+type OwnersFileNamer interface {
+	OwnersFileName(path string) string
+}
+
+// RequiredLabeler is implemented by repos whose OWNERS files can require
+// labels, e.g. for a review process. RequiredLabels returns the labels
+// that the PRs touching path must have, unlike RepoInterface.Labels which
+// are applied automatically.
+type RequiredLabeler interface {
+	RequiredLabels(path string) sets.String
+}
+
+// OwnersFileNamesSelector is implemented by repos that can read ownership
+// files with other names than their own, see NewOwners.
+// WithOwnersFileNames returns the repo reading the ownership files with the
+// given names, by precedence: only the first one found in a directory is
+// used.
+type OwnersFileNamesSelector interface {
+	WithOwnersFileNames(names []string) RepoInterface
+}
+
+// checkoutNamesSelector is implemented by features.RepoInfo, which returns
+// its own type, see OwnersFileNamesSelector.
+type checkoutNamesSelector interface {
+	WithOwnersFileNames(names []string) *features.RepoInfo
+}
+
+// withOwnersFileNames returns r reading the ownership files with the given
+// names, or r itself if it can't read other names.
+func withOwnersFileNames(r RepoInterface, names []string) RepoInterface {
+	switch selector := r.(type) {
+	case OwnersFileNamesSelector:
+		return selector.WithOwnersFileNames(names)
+	case checkoutNamesSelector:
+		return selector.WithOwnersFileNames(names)
+	}
+	glog.Warningf("Ignoring the ownership file names %v, the repo can't read other names", names)
+	return r
+}
+
+// OwnersLister is implemented by repos that can list their OWNERS files,
+// e.g. to audit them. ListOwnersDirs returns the directories with an OWNERS
+// file in root and its subdirectories.
+type OwnersLister interface {
+	ListOwnersDirs(root string) []string
+}
+
+// optionalOwnersLister is implemented by repos wrapping a repo that may be
+// an OwnersLister, e.g. RepoAlias.
+type optionalOwnersLister interface {
+	ListOwnersDirs(root string) ([]string, bool)
+}
+
+// ParseErrorReporter is implemented by repos that know which OWNERS files
+// failed to parse. ParseErrors returns the errors of the OWNERS files of
+// path's directory and its parents, by directory.
+type ParseErrorReporter interface {
+	ParseErrors(path string) map[string]error
+}
+
+// aliasValidator is implemented by repos that can find the names of an
+// OWNERS file that don't resolve to people, see RepoAlias.Validate.
+type aliasValidator interface {
+	Validate(path string) []string
+}
+
+// TeamExpander expands github team handles, e.g. @org/team, to the
+// logins of their members.
+type TeamExpander interface {
+	ExpandTeam(team string) sets.String
+}
+
+// BatchTeamExpander is implemented by TeamExpanders that can expand
+// several teams at once, e.g. to save API calls.
+type BatchTeamExpander interface {
+	TeamExpander
+	ExpandTeams(teams []string) map[string]sets.String
+}
+
+// teamPrefetcher is implemented by repos that can expand at once the teams
+// of a set of OWNERS files, before their people are looked up.
+type teamPrefetcher interface {
+	prefetchTeams(ownersFiles sets.String)
 }
 
 type RepoAlias struct {
 	repo  RepoInterface
 	alias features.Aliases
+	teams TeamExpander
+	// expandedTeams caches the members of the teams, as they are looked
+	// up for every OWNERS file. Lookups may outlive a cancelled context,
+	// so it is guarded by teamsLock.
+	expandedTeams map[string]sets.String
+	teamsLock     sync.Mutex
+	// CaseSensitive keeps the people listed with different cases apart,
+	// for approvers compared case-sensitively, see Approvers.CaseSensitive.
+	CaseSensitive bool
 }
 
 func NewRepoAlias(repo RepoInterface, alias features.Aliases) *RepoAlias {
@@ -55,59 +183,563 @@ func NewRepoAlias(repo RepoInterface, alias features.Aliases) *RepoAlias {
 	}
 }
 
+// NewRepoAliasWithTeams is like NewRepoAlias, but also expands the team
+// handles found in the OWNERS files with teams. The members of each team
+// are only looked up once, so a new RepoAlias should be created for each
+// evaluation.
+func NewRepoAliasWithTeams(repo RepoInterface, alias features.Aliases, teams TeamExpander) *RepoAlias {
+	r := NewRepoAlias(repo, alias)
+	r.teams = teams
+	r.expandedTeams = map[string]sets.String{}
+	return r
+}
+
+// isTeam returns true if name is a github team handle, e.g. @org/team.
+func isTeam(name string) bool {
+	return strings.HasPrefix(name, "@") && strings.Contains(name, "/")
+}
+
+// expand resolves the aliases and the team handles in people. People
+// listed with different cases, e.g. by two aliases, are listed once unless
+// r is CaseSensitive.
+func (r *RepoAlias) expand(people sets.String) sets.String {
+	expanded := r.alias.Expand(people)
+	if r.teams != nil {
+		for _, name := range expanded.List() {
+			if !isTeam(name) {
+				continue
+			}
+			expanded.Delete(name)
+			expanded.Insert(r.expandTeam(name).List()...)
+		}
+	}
+	if r.CaseSensitive {
+		return expanded
+	}
+	return uniqueIgnoringCase(expanded)
+}
+
+// uniqueIgnoringCase returns people with the logins differing only by
+// case listed once, with the first of them in sorted order.
+func uniqueIgnoringCase(people sets.String) sets.String {
+	seen := sets.NewString()
+	unique := sets.NewString()
+	for _, person := range people.List() {
+		if lower := strings.ToLower(person); !seen.Has(lower) {
+			seen.Insert(lower)
+			unique.Insert(person)
+		}
+	}
+	return unique
+}
+
+// prefetchTeams expands the teams of the approvers of ownersFiles that
+// aren't cached yet, in a single batch if the TeamExpander supports it.
+func (r *RepoAlias) prefetchTeams(ownersFiles sets.String) {
+	if r.teams == nil {
+		return
+	}
+	teams := sets.NewString()
+	for fn := range ownersFiles {
+		for name := range r.alias.Expand(r.repo.Approvers(fn)) {
+			if isTeam(name) {
+				teams.Insert(name)
+			}
+		}
+	}
+
+	r.teamsLock.Lock()
+	defer r.teamsLock.Unlock()
+	missing := []string{}
+	for _, team := range teams.List() {
+		if _, ok := r.expandedTeams[team]; !ok {
+			missing = append(missing, team)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	if batch, ok := r.teams.(BatchTeamExpander); ok {
+		expanded := batch.ExpandTeams(missing)
+		for _, team := range missing {
+			r.expandedTeams[team] = expanded[team]
+		}
+		return
+	}
+	for _, team := range missing {
+		r.expandedTeams[team] = r.teams.ExpandTeam(team)
+	}
+}
+
+// expandTeam returns the members of team, looking them up only once.
+func (r *RepoAlias) expandTeam(team string) sets.String {
+	r.teamsLock.Lock()
+	defer r.teamsLock.Unlock()
+	members, ok := r.expandedTeams[team]
+	if !ok {
+		members = r.teams.ExpandTeam(team)
+		r.expandedTeams[team] = members
+	}
+	return members
+}
+
 func (r *RepoAlias) Approvers(path string) sets.String {
-	return r.alias.Expand(r.repo.Approvers(path))
+	return r.expand(r.repo.Approvers(path))
 }
 
 func (r *RepoAlias) LeafApprovers(path string) sets.String {
-	return r.alias.Expand(r.repo.LeafApprovers(path))
+	return r.expand(r.repo.LeafApprovers(path))
+}
+
+func (r *RepoAlias) Reviewers(path string) sets.String {
+	return r.expand(r.repo.Reviewers(path))
+}
+
+func (r *RepoAlias) LeafReviewers(path string) sets.String {
+	return r.expand(r.repo.LeafReviewers(path))
 }
 func (r *RepoAlias) FindApproverOwnersForPath(path string) string {
 	return r.repo.FindApproverOwnersForPath(path)
 }
 
+func (r *RepoAlias) IsNoParentOwners(path string) bool {
+	return r.repo.IsNoParentOwners(path)
+}
+
+func (r *RepoAlias) EmeritusApprovers(path string) sets.String {
+	return r.expand(r.repo.EmeritusApprovers(path))
+}
+
+// Validate returns the approvers and reviewers of path that are neither
+// known aliases nor look like github logins, e.g. misspelled aliases that
+// would never approve. A warning is logged for each of them.
+func (r *RepoAlias) Validate(path string) []string {
+	unresolved := []string{}
+	for _, name := range r.repo.Approvers(path).Union(r.repo.Reviewers(path)).List() {
+		if r.alias.IsAlias(name) || githubLoginRegex.MatchString(name) || (r.teams != nil && isTeam(name)) {
+			continue
+		}
+		glog.Warningf("OWNERS for %q lists %q, which is neither an alias nor a github login", path, name)
+		unresolved = append(unresolved, name)
+	}
+	return unresolved
+}
+
+func (r *RepoAlias) OwnersFileName(path string) string {
+	if namer, ok := r.repo.(OwnersFileNamer); ok {
+		return namer.OwnersFileName(path)
+	}
+	return ""
+}
+
+func (r *RepoAlias) ParseErrors(path string) map[string]error {
+	if reporter, ok := r.repo.(ParseErrorReporter); ok {
+		return reporter.ParseErrors(path)
+	}
+	return nil
+}
+
+// WithOwnersFileNames returns a RepoAlias of the aliased repo reading the
+// ownership files with the given names, see OwnersFileNamesSelector. The
+// members of the teams are looked up again.
+func (r *RepoAlias) WithOwnersFileNames(names []string) RepoInterface {
+	selected := NewRepoAlias(withOwnersFileNames(r.repo, names), r.alias)
+	if r.teams != nil {
+		selected.teams = r.teams
+		selected.expandedTeams = map[string]sets.String{}
+	}
+	selected.CaseSensitive = r.CaseSensitive
+	return selected
+}
+
+// ListOwnersDirs returns the OWNERS directories listed by the aliased repo,
+// or false if it can't list them, see OwnersLister.
+func (r *RepoAlias) ListOwnersDirs(root string) ([]string, bool) {
+	if lister, ok := r.repo.(OwnersLister); ok {
+		return lister.ListOwnersDirs(root), true
+	}
+	return nil, false
+}
+
+func (r *RepoAlias) Labels(path string) sets.String {
+	return r.repo.Labels(path)
+}
+
+func (r *RepoAlias) RequiredLabels(path string) sets.String {
+	if labeler, ok := r.repo.(RequiredLabeler); ok {
+		return labeler.RequiredLabels(path)
+	}
+	return sets.NewString()
+}
+
+func (r *RepoAlias) RequiredApprovals(path string) int {
+	return r.repo.RequiredApprovals(path)
+}
+
+func (r *RepoAlias) RequiredApprovers(path string) sets.String {
+	return r.expand(r.repo.RequiredApprovers(path))
+}
+
+func (r *RepoAlias) PrimaryApprovers(path string) sets.String {
+	return r.expand(r.repo.PrimaryApprovers(path))
+}
+
+func (r *RepoAlias) BackupApprovers(path string) sets.String {
+	return r.expand(r.repo.BackupApprovers(path))
+}
+
 type Owners struct {
 	filenames []string
 	repo      RepoInterface
 	seed      int64
+
+	// loadFunc returns the current review load of a person, see
+	// Approvers.LoadFunc.
+	loadFunc func(login string) int
+	// activeFunc returns false for inactive people, see
+	// Approvers.ActiveFunc.
+	activeFunc func(login string) bool
+	// minimizeSpread suggests approvers with GetMinimalSpreadApprovers,
+	// see Approvers.MinimizeSpread.
+	minimizeSpread bool
+	// excluded are never suggested, see Approvers.ExcludedApprovers.
+	excluded sets.String
+	// availableFunc returns false for people who can't be suggested, see
+	// Approvers.AvailableFunc.
+	availableFunc func(login string) bool
+	// tieBreak picks between approvers covering as many files, see
+	// Approvers.TieBreak.
+	tieBreak func(a, b string) bool
+	// cooldownFunc returns true for people who are only suggested if
+	// needed, see Approvers.CooldownFunc.
+	cooldownFunc func(login string) bool
+	// rootPenalty is Approvers.RootCoveragePenalty, only set when
+	// keeping assignees.
+	rootPenalty float64
+	// stats collects the work done by the suggestions, nil unless
+	// they're requested with GetCCsWithStats.
+	stats *SuggestionStats
+	// ignoreRequiredApprovers considers files approved without their
+	// required approvers, as suggestions add them separately.
+	ignoreRequiredApprovers bool
+	// approvals are the approvals of the PR, so that the files that a
+	// current approver would approve keep their cancelled paths, see
+	// temporaryUnapprovedFiles. Set by Approvers.suggestionOwners.
+	approvals map[string]Approval
+	// caseSensitive is Approvers.CaseSensitive.
+	caseSensitive bool
+	// extraRequired are required approvers added to OWNERS files for
+	// this PR only, see Approvers.RequireExtraApproverForPath.
+	extraRequired map[string]sets.String
+	// cache is nil if the results shouldn't be cached.
+	cache *ownersCache
+}
+
+// ownersCache memoizes the repo lookups done by an Owners, as they are
+// requested many times while evaluating a PR.
+type ownersCache struct {
+	ownersSetOnce     sync.Once
+	ownersSet         sets.String
+	approversOnce     sync.Once
+	approvers         map[string]sets.String
+	leafApproversOnce sync.Once
+	leafApprovers     map[string]sets.String
+	rootCoverOnce     sync.Once
+	rootApprovers     sets.String
+	rootRequired      bool
+	detailsLock       sync.Mutex
+	details           map[string]ownersDetails
+}
+
+// ownersDetails holds the repo lookups of a single OWNERS file, besides
+// its approvers.
+type ownersDetails struct {
+	labels            sets.String
+	requiredLabels    sets.String
+	emeritus          sets.String
+	primary           sets.String
+	required          sets.String
+	requiredApprovals int
+}
+
+// lookupDetails looks up the details of the given OWNERS file in repo.
+func lookupDetails(repo RepoInterface, ownersFile string) ownersDetails {
+	return ownersDetails{
+		labels:            repo.Labels(ownersFile),
+		requiredLabels:    lookupRequiredLabels(repo, ownersFile),
+		emeritus:          repo.EmeritusApprovers(ownersFile),
+		primary:           repo.PrimaryApprovers(ownersFile),
+		required:          repo.RequiredApprovers(ownersFile),
+		requiredApprovals: repo.RequiredApprovals(ownersFile),
+	}
+}
+
+// lookupRequiredLabels returns the labels required by the given OWNERS
+// file, if repo is a RequiredLabeler.
+func lookupRequiredLabels(repo RepoInterface, ownersFile string) sets.String {
+	if labeler, ok := repo.(RequiredLabeler); ok {
+		return labeler.RequiredLabels(ownersFile)
+	}
+	return sets.NewString()
+}
+
+// cachedDetails returns the details of the given OWNERS file, looking them
+// up if they aren't cached yet. It returns false if o doesn't cache its
+// lookups.
+func (o Owners) cachedDetails(ownersFile string) (ownersDetails, bool) {
+	if o.cache == nil {
+		return ownersDetails{}, false
+	}
+	o.cache.detailsLock.Lock()
+	details, ok := o.cache.details[ownersFile]
+	o.cache.detailsLock.Unlock()
+	if !ok {
+		details = lookupDetails(o.repo, ownersFile)
+		o.cache.setDetails(ownersFile, details)
+	}
+	return details, true
+}
+
+func (c *ownersCache) setDetails(ownersFile string, details ownersDetails) {
+	c.detailsLock.Lock()
+	defer c.detailsLock.Unlock()
+	if c.details == nil {
+		c.details = map[string]ownersDetails{}
+	}
+	c.details[ownersFile] = details
+}
+
+// labels returns the labels listed in the given OWNERS file.
+func (o Owners) labels(ownersFile string) sets.String {
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		return details.labels
+	}
+	return o.repo.Labels(ownersFile)
+}
+
+// requiredLabels returns the labels required by the given OWNERS file, see
+// RequiredLabeler.
+func (o Owners) requiredLabels(ownersFile string) sets.String {
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		return details.requiredLabels
+	}
+	return lookupRequiredLabels(o.repo, ownersFile)
+}
+
+// emeritusApprovers returns the emeritus approvers of the given OWNERS file.
+func (o Owners) emeritusApprovers(ownersFile string) sets.String {
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		return details.emeritus
+	}
+	return o.repo.EmeritusApprovers(ownersFile)
+}
+
+// primaryApprovers returns the primary approvers of the given OWNERS file.
+func (o Owners) primaryApprovers(ownersFile string) sets.String {
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		return details.primary
+	}
+	return o.repo.PrimaryApprovers(ownersFile)
+}
+
+// requiredApprovers returns the required approvers of the given OWNERS
+// file.
+func (o Owners) requiredApprovers(ownersFile string) sets.String {
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		return details.required
+	}
+	return o.repo.RequiredApprovers(ownersFile)
+}
+
+// NewOwners creates an Owners for the given files. Repo lookups are
+// cached for the lifetime of the returned Owners. The ownership files are
+// read with the given names, by precedence, if r is an
+// OwnersFileNamesSelector: only the first one found in a directory is used.
+// Without names, r reads its own ownership files, usually OWNERS.
+func NewOwners(filenames []string, r RepoInterface, s int64, ownersFileNames ...string) Owners {
+	if len(ownersFileNames) != 0 {
+		r = withOwnersFileNames(r, ownersFileNames)
+	}
+	return Owners{filenames: filenames, repo: r, seed: s, cache: &ownersCache{}}
+}
+
+// NewOwnersValidated is NewOwners for filenames coming from an untrusted
+// source. Filenames are cleaned and deduplicated, and an error is returned
+// for empty filenames or filenames outside of the repository.
+func NewOwnersValidated(filenames []string, r RepoInterface, s int64, ownersFileNames ...string) (Owners, error) {
+	cleaned := []string{}
+	seen := sets.NewString()
+	for _, fn := range filenames {
+		if fn == "" {
+			return Owners{}, fmt.Errorf("Empty filename")
+		}
+		clean := filepath.Clean(fn)
+		if clean == ".." || strings.HasPrefix(clean, "../") || filepath.IsAbs(clean) {
+			return Owners{}, fmt.Errorf("Filename %s is outside of the repository", fn)
+		}
+		if seen.Has(clean) {
+			continue
+		}
+		seen.Insert(clean)
+		cleaned = append(cleaned, clean)
+	}
+	return NewOwners(cleaned, r, s, ownersFileNames...), nil
 }
 
-func NewOwners(filenames []string, r RepoInterface, s int64) Owners {
-	return Owners{filenames: filenames, repo: r, seed: s}
+// SeedForPR returns a seed for NewOwners derived from the PR, so that
+// suggestions are stable across runs for a PR but vary between PRs.
+func SeedForPR(org, project string, number int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%s#%d", org, project, number)
+	return int64(h.Sum64())
 }
 
 // GetApprovers returns a map from ownersFiles -> people that are approvers in them
+// The result may be cached and must not be modified.
 func (o Owners) GetApprovers() map[string]sets.String {
-	ownersToApprovers := map[string]sets.String{}
-
-	for fn := range o.GetOwnersSet() {
-		ownersToApprovers[fn] = o.repo.Approvers(fn)
+	if o.cache == nil {
+		return o.getApprovers()
 	}
+	o.cache.approversOnce.Do(func() { o.cache.approvers = o.getApprovers() })
+	return o.cache.approvers
+}
 
+func (o Owners) getApprovers() map[string]sets.String {
+	ownersToApprovers, _ := o.getApproversContext(context.Background(), o.GetOwnersSet())
 	return ownersToApprovers
 }
 
+func (o Owners) getApproversContext(ctx context.Context, ownersSet sets.String) (map[string]sets.String, error) {
+	if prefetcher, ok := o.repo.(teamPrefetcher); ok {
+		prefetcher.prefetchTeams(ownersSet)
+	}
+	return lookupEach(ctx, ownersSet, withContext(o.repo).ApproversContext)
+}
+
 // GetLeafApprovers returns a map from ownersFiles -> people that are approvers in them (only the leaf)
+// The result may be cached and must not be modified.
 func (o Owners) GetLeafApprovers() map[string]sets.String {
-	ownersToApprovers := map[string]sets.String{}
+	if o.cache == nil {
+		return o.getLeafApprovers()
+	}
+	o.cache.leafApproversOnce.Do(func() { o.cache.leafApprovers = o.getLeafApprovers() })
+	return o.cache.leafApprovers
+}
+
+func (o Owners) getLeafApprovers() map[string]sets.String {
+	ownersToApprovers, _ := o.getLeafApproversContext(context.Background(), o.GetOwnersSet())
+	return ownersToApprovers
+}
+
+func (o Owners) getLeafApproversContext(ctx context.Context, ownersSet sets.String) (map[string]sets.String, error) {
+	if prefetcher, ok := o.repo.(teamPrefetcher); ok {
+		prefetcher.prefetchTeams(ownersSet)
+	}
+	return lookupEach(ctx, ownersSet, withContext(o.repo).LeafApproversContext)
+}
+
+// rootCover returns the approvers of the root OWNERS file if any of them
+// can approve every OWNERS file of the PR alone, i.e. if none requires
+// several approvals or is under a directory with the no_parent_owners
+// option. It's empty otherwise. required is true if an OWNERS file of the
+// PR has required approvers.
+func (o Owners) rootCover() (approvers sets.String, required bool) {
+	if o.cache == nil {
+		return o.getRootCover()
+	}
+	o.cache.rootCoverOnce.Do(func() { o.cache.rootApprovers, o.cache.rootRequired = o.getRootCover() })
+	return o.cache.rootApprovers, o.cache.rootRequired
+}
+
+func (o Owners) getRootCover() (approvers sets.String, required bool) {
+	approvers, required, _ = o.getRootCoverContext(context.Background(), o.GetOwnersSet())
+	return approvers, required
+}
+
+// GetLeafReviewers returns a map from ownersFiles -> people that are reviewers in them (only the leaf)
+func (o Owners) GetLeafReviewers() map[string]sets.String {
+	ownersToReviewers := map[string]sets.String{}
 
 	for fn := range o.GetOwnersSet() {
-		ownersToApprovers[fn] = o.repo.LeafApprovers(fn)
+		ownersToReviewers[fn] = o.repo.LeafReviewers(fn)
 	}
 
-	return ownersToApprovers
+	return ownersToReviewers
+}
+
+// GetRequiredLabels returns the labels listed in the OWNERS files of the
+// PR, that should be applied to it.
+func (o Owners) GetRequiredLabels() sets.String {
+	labels := sets.NewString()
+	for fn := range o.GetOwnersSet() {
+		labels = labels.Union(o.labels(fn))
+	}
+	return labels
 }
 
 // GetAllPotentialApprovers returns the people from relevant owners files needed to get the PR approved
+// Emeritus approvers are not included, as we never want to suggest them.
+// Each person is listed once, even if they are in several OWNERS files.
 func (o Owners) GetAllPotentialApprovers() []string {
-	approversOnly := []string{}
-	for _, approverList := range o.GetLeafApprovers() {
-		for approver := range approverList {
-			approversOnly = append(approversOnly, approver)
+	approversOnly := sets.NewString()
+	for fn, approverList := range o.getSuggestableApprovers() {
+		approversOnly = approversOnly.Union(approverList.Difference(o.emeritusApprovers(fn)))
+	}
+	return approversOnly.List()
+}
+
+// getSuggestableApprovers returns the leaf approvers of each OWNERS file,
+// except for files whose leaf approvers are all inactive: these escalate
+// to their active approvers, including the ones of the parent OWNERS
+// files. Backup approvers are left out of the files that a primary
+// approver can approve.
+func (o Owners) getSuggestableApprovers() map[string]sets.String {
+	suggestable := map[string]sets.String{}
+	for fn, leaves := range o.GetLeafApprovers() {
+		suggestable[fn] = leaves
+		if o.activeFunc != nil && !o.anyActive(leaves) {
+			if active := o.activeOnly(o.GetApprovers()[fn]); active.Len() != 0 {
+				suggestable[fn] = active
+			}
+		}
+		suggestable[fn] = o.preferPrimary(fn, suggestable[fn])
+	}
+	return suggestable
+}
+
+// preferPrimary returns the primary approvers among people, or people if
+// none of them can be suggested, so that backup approvers are only
+// suggested when no primary approver can approve the OWNERS file.
+func (o Owners) preferPrimary(ownersFile string, people sets.String) sets.String {
+	primary := people.Intersection(o.primaryApprovers(ownersFile)).Difference(o.emeritusApprovers(ownersFile))
+	if o.activeFunc != nil {
+		primary = o.activeOnly(primary)
+	}
+	if len(o.withoutExcluded(primary.List())) == 0 {
+		return people
+	}
+	return primary
+}
+
+func (o Owners) anyActive(people sets.String) bool {
+	return o.activeOnly(people).Len() != 0
+}
+
+// activeOnly returns the people for whom activeFunc returns true.
+func (o Owners) activeOnly(people sets.String) sets.String {
+	active := sets.NewString()
+	for person := range people {
+		if o.activeFunc(person) {
+			active.Insert(person)
 		}
 	}
-	sort.Strings(approversOnly)
-	return approversOnly
+	return active
+}
+
+// PotentialApproverCount returns the number of distinct people who can
+// be suggested to approve the PR.
+func (o Owners) PotentialApproverCount() int {
+	return len(o.GetAllPotentialApprovers())
 }
 
 // GetReverseMap returns a map from people -> OWNERS files for which they are an approver
@@ -125,25 +757,104 @@ func (o Owners) GetReverseMap(approvers map[string]sets.String) map[string]sets.
 	return approverOwnersfiles
 }
 
+// findMostCoveringApprover returns the person from allApprovers who covers the
+// most files in unapproved. It works with any people -> files reverse map, so
+// it's also used to find reviewers.
 func findMostCoveringApprover(allApprovers []string, reverseMap map[string]sets.String, unapproved sets.String) string {
 	maxCovered := 0
 	var bestPerson string
 	for _, approver := range allApprovers {
 		filesCanApprove := reverseMap[approver]
-		if filesCanApprove.Intersection(unapproved).Len() > maxCovered {
-			maxCovered = len(filesCanApprove)
+		covered := filesCanApprove.Intersection(unapproved).Len()
+		if covered > maxCovered {
+			maxCovered = covered
+			bestPerson = approver
+		}
+	}
+	return bestPerson
+}
+
+// mostCoveringApprover is findMostCoveringApprover, but when a load function
+// is set, people with a higher load are slightly penalized and ties go to
+// the person with the lowest load. Remaining ties are broken with tieBreak
+// if set. Files that a person would only approve as an approver of a parent
+// OWNERS file count rootPenalty less.
+//
+// People cooling down are only picked if nobody else covers any file.
+func (o Owners) mostCoveringApprover(allApprovers []string, reverseMap map[string]sets.String, unapproved sets.String) string {
+	if o.cooldownFunc != nil {
+		warm, cooling := []string{}, []string{}
+		for _, approver := range allApprovers {
+			if o.cooldownFunc(approver) {
+				cooling = append(cooling, approver)
+			} else {
+				warm = append(warm, approver)
+			}
+		}
+		o.cooldownFunc = nil
+		if best := o.mostCoveringApprover(warm, reverseMap, unapproved); best != "" {
+			return best
+		}
+		return o.mostCoveringApprover(cooling, reverseMap, unapproved)
+	}
+	if o.loadFunc == nil && o.tieBreak == nil && o.rootPenalty == 0 {
+		return findMostCoveringApprover(allApprovers, reverseMap, unapproved)
+	}
+
+	var bestPerson string
+	var bestScore float64
+	var bestLoad int
+	for _, approver := range allApprovers {
+		covered := reverseMap[approver].Intersection(unapproved).Len()
+		if covered == 0 {
+			continue
+		}
+		load := 0
+		if o.loadFunc != nil {
+			load = o.loadFunc(approver)
+		}
+		score := float64(covered) - loadPenalty*float64(load) - o.rootPenalty*float64(o.nonLeafCount(approver, reverseMap[approver].Intersection(unapproved)))
+		tied := score == bestScore && load == bestLoad
+		if bestPerson == "" || score > bestScore || (score == bestScore && load < bestLoad) || (tied && o.tieBreak != nil && o.tieBreak(approver, bestPerson)) {
 			bestPerson = approver
+			bestScore = score
+			bestLoad = load
 		}
 	}
 	return bestPerson
 }
 
+// nonLeafCount returns the number of ownersFiles that don't list person as
+// a leaf approver.
+func (o Owners) nonLeafCount(person string, ownersFiles sets.String) int {
+	if o.rootPenalty == 0 {
+		return 0
+	}
+	leafApprovers := o.GetLeafApprovers()
+	count := 0
+	for fn := range ownersFiles {
+		if !leafApprovers[fn].Has(person) {
+			count++
+		}
+	}
+	return count
+}
+
 // temporaryUnapprovedFiles returns the list of files that wouldn't be
-// approved by the given set of approvers.
+// approved by the given set of approvers. The people who already
+// approved keep their actual approval, e.g. without its cancelled
+// paths.
 func (o Owners) temporaryUnapprovedFiles(approvers sets.String) sets.String {
 	ap := NewApprovers(o)
-	for approver := range approvers {
-		ap.AddApprover(approver, "")
+	ap.CaseSensitive = o.caseSensitive
+	// Add them in order, so that the same person listed with different
+	// cases always ends up with the same login.
+	for _, approver := range approvers.List() {
+		if approval, ok := o.approvals[ap.approvalKey(approver)]; ok && approval.Login == approver {
+			ap.addApproval(approval)
+			continue
+		}
+		ap.AddApprover(approver, "", "")
 	}
 	return ap.UnapprovedFiles()
 }
@@ -155,94 +866,880 @@ func (o Owners) KeepCoveringApprovers(reverseMap map[string]sets.String, knownAp
 
 	unapproved := o.temporaryUnapprovedFiles(knownApprovers)
 
-	for _, suggestedApprover := range o.GetSuggestedApprovers(reverseMap, potentialApprovers).List() {
+	for _, suggestedApprover := range o.suggestApprovers(reverseMap, potentialApprovers).List() {
+		if knownApprovers.Has(suggestedApprover) {
+			continue
+		}
 		if reverseMap[suggestedApprover].Intersection(unapproved).Len() != 0 {
 			keptApprovers.Insert(suggestedApprover)
+			if o.requiresSeveralApprovals() {
+				// Only keep the people still needed to reach
+				// the required approvals.
+				unapproved = o.temporaryUnapprovedFiles(knownApprovers.Union(keptApprovers))
+			}
 		}
 	}
 
 	return keptApprovers
 }
 
+// requiredApprovals returns the number of approvers needed to approve the
+// given OWNERS file.
+func (o Owners) requiredApprovals(ownersFile string) int {
+	required := 0
+	if details, ok := o.cachedDetails(ownersFile); ok {
+		required = details.requiredApprovals
+	} else {
+		required = o.repo.RequiredApprovals(ownersFile)
+	}
+	if required > 1 {
+		return required
+	}
+	return 1
+}
+
+// requiresSeveralApprovals returns true if one of the OWNERS files needs
+// more than one approver.
+func (o Owners) requiresSeveralApprovals() bool {
+	for fn := range o.GetOwnersSet() {
+		if o.requiredApprovals(fn) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
 // GetSuggestedApprovers solves the exact cover problem, finding an approver capable of
 // approving every OWNERS file in the PR
 func (o Owners) GetSuggestedApprovers(reverseMap map[string]sets.String, potentialApprovers []string) sets.String {
+	suggested, uncoverable := o.GetSuggestedApproversE(reverseMap, potentialApprovers)
+	if uncoverable.Len() != 0 {
+		glog.Errorf("Couldn't find/suggest approvers for each files. Unapproved: %s", uncoverable)
+	}
+	return suggested
+}
+
+// GetSuggestedApproversE is like GetSuggestedApprovers, but returns the
+// OWNERS files that none of potentialApprovers can approve instead of
+// logging them, e.g. so that the PR can be labeled for an OWNERS fix.
+func (o Owners) GetSuggestedApproversE(reverseMap map[string]sets.String, potentialApprovers []string) (suggested, uncoverable sets.String) {
 	ap := NewApprovers(o)
+	ap.CaseSensitive = o.caseSensitive
 	for !ap.IsApproved() {
-		newApprover := findMostCoveringApprover(potentialApprovers, reverseMap, ap.UnapprovedFiles())
+		if o.stats != nil {
+			o.stats.Iterations++
+		}
+		// Files requiring several approvals stay unapproved after the
+		// first one, don't pick the same person twice.
+		candidates := []string{}
+		for _, approver := range potentialApprovers {
+			if !ap.GetCurrentApproversSet().Has(approver) {
+				candidates = append(candidates, approver)
+			}
+		}
+		newApprover := o.mostCoveringApprover(candidates, reverseMap, ap.UnapprovedFiles())
 		if newApprover == "" {
-			glog.Errorf("Couldn't find/suggest approvers for each files. Unapproved: %s", ap.UnapprovedFiles())
-			return ap.GetCurrentApproversSet()
+			return ap.GetCurrentApproversSet(), ap.UnapprovedFiles()
 		}
-		ap.AddApprover(newApprover, "")
+		ap.AddApprover(newApprover, "", "")
 	}
 
-	return ap.GetCurrentApproversSet()
+	return ap.GetCurrentApproversSet(), sets.NewString()
 }
 
-// GetOwnersSet returns a set containing all the Owners files necessary to get the PR approved
-func (o Owners) GetOwnersSet() sets.String {
-	owners := sets.NewString()
-	for _, fn := range o.filenames {
-		owners.Insert(o.repo.FindApproverOwnersForPath(fn))
+// suggestApprovers returns the approvers covering every OWNERS file in the
+// PR, with GetMinimalSpreadApprovers if minimizeSpread is set or
+// GetSuggestedApprovers otherwise.
+func (o Owners) suggestApprovers(reverseMap map[string]sets.String, potentialApprovers []string) sets.String {
+	if o.minimizeSpread {
+		return o.GetMinimalSpreadApprovers(reverseMap, potentialApprovers)
 	}
-	return removeSubdirs(owners.List())
+	return o.GetSuggestedApprovers(reverseMap, potentialApprovers)
 }
 
-// Shuffles the potential approvers so that we don't always suggest the same people
-func (o Owners) GetShuffledApprovers() []string {
-	approversList := o.GetAllPotentialApprovers()
-	order := rand.New(rand.NewSource(o.seed)).Perm(len(approversList))
-	people := make([]string, 0, len(approversList))
-	for _, i := range order {
-		people = append(people, approversList[i])
+// GetMinimalApprovers finds the smallest set of approvers capable of
+// approving every OWNERS file in the PR. The search is exact when the PR
+// has at most MinimalApproversThreshold OWNERS files and it explores at
+// most MaxCoverSearchNodes covers, otherwise it falls back to the greedy
+// GetSuggestedApprovers, or the best cover found.
+func (o Owners) GetMinimalApprovers(reverseMap map[string]sets.String, potentialApprovers []string) sets.String {
+	return o.getMinimalApprovers(reverseMap, potentialApprovers, nil)
+}
+
+// GetMinimalSpreadApprovers is GetMinimalApprovers, but among the smallest
+// sets of approvers it prefers the one with the fewest teams, so that
+// fewer teams need to coordinate. The team of an approver is the
+// top-level directory of most of the OWNERS files they can approve.
+func (o Owners) GetMinimalSpreadApprovers(reverseMap map[string]sets.String, potentialApprovers []string) sets.String {
+	return o.getMinimalApprovers(reverseMap, potentialApprovers, func(people []string) int {
+		return teamSpread(people, reverseMap)
+	})
+}
+
+// getMinimalApprovers finds the smallest set of approvers, breaking ties
+// with the lowest spread if spread is not nil.
+func (o Owners) getMinimalApprovers(reverseMap map[string]sets.String, potentialApprovers []string, spread func([]string) int) sets.String {
+	ownersFiles := o.GetOwnersSet()
+	if ownersFiles.Len() > MinimalApproversThreshold || o.requiresSeveralApprovals() {
+		return o.GetSuggestedApprovers(reverseMap, potentialApprovers)
+	}
+
+	coverable := sets.NewString()
+	for _, approver := range potentialApprovers {
+		coverable = coverable.Union(reverseMap[approver].Intersection(ownersFiles))
 	}
-	return people
+	if !coverable.Equal(ownersFiles) {
+		glog.Errorf("Couldn't find/suggest approvers for each files. Unapproved: %s", ownersFiles.Difference(coverable))
+	}
+
+	greedy := o.GetSuggestedApprovers(reverseMap, potentialApprovers)
+	return sets.NewString(findMinimalCover(potentialApprovers, reverseMap, coverable, greedy.List(), spread)...)
 }
 
-// removeSubdirs takes a list of directories as an input and returns a set of directories with all
-// subdirectories removed.  E.g. [/a,/a/b/c,/d/e,/d/e/f] -> [/a, /d/e]
-func removeSubdirs(dirList []string) sets.String {
-	toDel := sets.String{}
-	for i := 0; i < len(dirList)-1; i++ {
-		for j := i + 1; j < len(dirList); j++ {
-			// ex /a/b has prefix /a so if remove /a/b since its already covered
-			if strings.HasPrefix(dirList[i], dirList[j]) {
-				toDel.Insert(dirList[i])
-			} else if strings.HasPrefix(dirList[j], dirList[i]) {
-				toDel.Insert(dirList[j])
-			}
+// teamSpread returns the number of distinct teams of people, see
+// GetMinimalSpreadApprovers.
+func teamSpread(people []string, reverseMap map[string]sets.String) int {
+	teams := sets.NewString()
+	for _, person := range people {
+		teams.Insert(team(reverseMap[person]))
+	}
+	return teams.Len()
+}
+
+// team returns the top-level directory of most of the OWNERS files, the
+// first one in case of a tie.
+func team(ownersFiles sets.String) string {
+	count := map[string]int{}
+	var best string
+	for _, fn := range ownersFiles.List() {
+		dir, _ := features.SplitOwnersKey(fn)
+		top := strings.SplitN(dir, "/", 2)[0]
+		count[top]++
+		if count[top] > count[best] {
+			best = top
 		}
 	}
-	finalSet := sets.NewString(dirList...)
-	finalSet.Delete(toDel.List()...)
-	return finalSet
+	return best
 }
 
-// Approval has the information about each approval on a PR
-type Approval struct {
-	Login     string // Login of the approver
-	How       string // How did the approver approved
-	Reference string // Where did the approver approved
+// findMinimalCover does a branch-and-bound search for the smallest list of
+// people covering all the uncovered files, starting from the initial
+// cover, e.g. the greedy one, or a greedy cover if initial doesn't cover
+// them. If spread is not nil, the smallest cover with the lowest spread is
+// found; spread must count distinct groups of people, like teamSpread.
+// Past MaxCoverSearchNodes, the best cover found so far is returned.
+func findMinimalCover(people []string, reverseMap map[string]sets.String, uncovered sets.String, initial []string, spread func([]string) int) []string {
+	search := &coverSearch{people: people, reverseMap: reverseMap, spread: spread}
+	if coversAll(initial, reverseMap, uncovered) {
+		search.setBest(initial)
+	} else {
+		search.setBest(greedyCover(people, reverseMap, uncovered))
+	}
+	search.search(uncovered, []string{})
+	return search.best
 }
 
-// String creates a link for the approval. Use `Login` if you just want the name.
-func (a Approval) String() string {
-	return fmt.Sprintf(
-		`*<a href="%s" title="%s">%s</a>*`,
-		a.Reference,
-		a.How,
-		a.Login,
-	)
+// MaxCoverSearchNodes caps the number of partial covers explored by
+// findMinimalCover, so that large PRs don't take forever.
+var MaxCoverSearchNodes = 100000
+
+// coverSearch holds the state of findMinimalCover.
+type coverSearch struct {
+	people     []string
+	reverseMap map[string]sets.String
+	spread     func([]string) int
+	best       []string
+	bestSpread int
+	nodes      int
 }
 
-type Approvers struct {
-	owners    Owners
-	approvers map[string]Approval
-	assignees sets.String
+func (c *coverSearch) setBest(cover []string) {
+	c.best = append([]string{}, cover...)
+	if c.spread != nil {
+		c.bestSpread = c.spread(c.best)
+	}
 }
 
-// IntersectSetsCase runs the intersection between to sets.String in a
+// search explores the covers of uncovered given that chosen are already
+// picked, keeping the best one.
+func (c *coverSearch) search(uncovered sets.String, chosen []string) {
+	if c.nodes >= MaxCoverSearchNodes {
+		return
+	}
+	c.nodes++
+	if uncovered.Len() == 0 {
+		if len(chosen) < len(c.best) || (c.spread != nil && len(chosen) == len(c.best) && c.spread(chosen) < c.bestSpread) {
+			c.setBest(chosen)
+		}
+		return
+	}
+
+	// Nobody covers more than maxCoverage of the uncovered files, so at
+	// least needed more people are needed. Covers of the same size can
+	// still have a lower spread.
+	maxCoverage := 0
+	for _, person := range c.people {
+		if covered := c.reverseMap[person].Intersection(uncovered).Len(); covered > maxCoverage {
+			maxCoverage = covered
+		}
+	}
+	if maxCoverage == 0 {
+		return
+	}
+	needed := len(chosen) + (uncovered.Len()+maxCoverage-1)/maxCoverage
+	if needed > len(c.best) || (needed == len(c.best) && (c.spread == nil || c.bestSpread <= 1)) {
+		return
+	}
+
+	for _, person := range c.candidates(uncovered) {
+		c.search(uncovered.Difference(c.reverseMap[person]), append(chosen, person))
+	}
+}
+
+// candidates returns the people covering the uncovered file that the
+// fewest people can cover, leaving out the people whose uncovered files
+// are all covered by another candidate, who can replace them in any cover.
+func (c *coverSearch) candidates(uncovered sets.String) []string {
+	var canCover []string
+	for _, fn := range uncovered.List() {
+		people := []string{}
+		for _, person := range c.people {
+			if c.reverseMap[person].Has(fn) {
+				people = append(people, person)
+			}
+		}
+		if canCover == nil || len(people) < len(canCover) {
+			canCover = people
+		}
+	}
+
+	candidates := []string{}
+	for i, person := range canCover {
+		dominated := false
+		for j, other := range canCover {
+			if i != j && c.dominates(other, person, uncovered) && (j < i || !c.dominates(person, other, uncovered)) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			candidates = append(candidates, person)
+		}
+	}
+	return candidates
+}
+
+// dominates returns true if other covers the uncovered files of person
+// without increasing the spread.
+func (c *coverSearch) dominates(other, person string, uncovered sets.String) bool {
+	if !c.reverseMap[other].IsSuperset(c.reverseMap[person].Intersection(uncovered)) {
+		return false
+	}
+	return c.spread == nil || c.spread([]string{person, other}) == 1
+}
+
+// coversAll returns true if people cover all the uncovered files.
+func coversAll(people []string, reverseMap map[string]sets.String, uncovered sets.String) bool {
+	if people == nil {
+		return false
+	}
+	for _, person := range people {
+		uncovered = uncovered.Difference(reverseMap[person])
+	}
+	return uncovered.Len() == 0
+}
+
+// greedyCover covers the uncovered files by picking the person covering
+// the most of them, in the order of people in case of a tie.
+func greedyCover(people []string, reverseMap map[string]sets.String, uncovered sets.String) []string {
+	cover := []string{}
+	for uncovered.Len() != 0 {
+		picked, pickedCoverage := "", 0
+		for _, person := range people {
+			if covered := reverseMap[person].Intersection(uncovered).Len(); covered > pickedCoverage {
+				picked, pickedCoverage = person, covered
+			}
+		}
+		if picked == "" {
+			break
+		}
+		cover = append(cover, picked)
+		uncovered = uncovered.Difference(reverseMap[picked])
+	}
+	return cover
+}
+
+// GetSuggestedReviewers finds reviewers covering every OWNERS file in the PR,
+// independently from the approvers.
+func (o Owners) GetSuggestedReviewers() sets.String {
+	leafReviewers := o.GetLeafReviewers()
+	potentialReviewers := sets.NewString()
+	for _, reviewers := range leafReviewers {
+		potentialReviewers = potentialReviewers.Union(reviewers)
+	}
+	reverseMap := o.GetReverseMap(leafReviewers)
+	shuffled := o.shuffle(potentialReviewers.List())
+
+	suggested := sets.NewString()
+	unreviewed := o.GetOwnersSet()
+	for unreviewed.Len() != 0 {
+		newReviewer := o.mostCoveringApprover(shuffled, reverseMap, unreviewed)
+		if newReviewer == "" {
+			glog.Errorf("Couldn't find/suggest reviewers for each files. Unreviewed: %s", unreviewed)
+			return suggested
+		}
+		suggested.Insert(newReviewer)
+		unreviewed = unreviewed.Difference(reverseMap[newReviewer])
+	}
+
+	return suggested
+}
+
+// GetOwnersSet returns a set containing all the Owners files necessary to get the PR approved
+// The result may be cached and must not be modified.
+func (o Owners) GetOwnersSet() sets.String {
+	if o.cache == nil {
+		return o.getOwnersSet()
+	}
+	o.cache.ownersSetOnce.Do(func() { o.cache.ownersSet = o.getOwnersSet() })
+	return o.cache.ownersSet
+}
+
+func (o Owners) getOwnersSet() sets.String {
+	owners, _ := o.getOwnersSetContext(context.Background())
+	return owners
+}
+
+func (o Owners) getOwnersSetContext(ctx context.Context) (sets.String, error) {
+	repo := withContext(o.repo)
+	owners := sets.NewString()
+	for _, fn := range o.filenames {
+		ownersFile, err := repo.FindApproverOwnersForPathContext(ctx, normalizePath(fn))
+		if err != nil {
+			return nil, err
+		}
+		owners.Insert(ownersFile)
+	}
+	return removeSubdirs(owners.List(), o.repo.IsNoParentOwners), nil
+}
+
+// normalizePath returns path with forward slashes, without ./ or .. segments
+// and doubled or trailing slashes, so that the same file or directory
+// always has the same path. The root is "".
+func normalizePath(path string) string {
+	path = filepath.ToSlash(filepath.Clean(strings.Replace(path, "\\", "/", -1)))
+	if path == "." {
+		return ""
+	}
+	return path
+}
+
+// normalizeOwnersKey is normalizePath for the OWNERS keys returned by
+// FindApproverOwnersForPath, which may end with a filter pattern.
+func normalizeOwnersKey(key string) string {
+	dir, filter := features.SplitOwnersKey(key)
+	if filter != "" {
+		return features.FilteredOwnersKey(normalizePath(dir), filter)
+	}
+	return normalizePath(dir)
+}
+
+// coveringOwnersFile returns the OWNERS file of GetOwnersSet approving the
+// OWNERS file fn: fn itself, or the closest parent it was merged into, see
+// removeSubdirs. fn is returned if none covers it.
+func (o Owners) coveringOwnersFile(fn string) string {
+	ownersSet := o.GetOwnersSet()
+	if ownersSet.Has(fn) {
+		return fn
+	}
+	covering, found := fn, false
+	for _, parent := range ownersSet.List() {
+		if isSubdir(parent, fn) && (!found || len(parent) > len(covering)) {
+			covering, found = parent, true
+		}
+	}
+	return covering
+}
+
+// findOwnersForPath returns the OWNERS key of the normalized path.
+func (o Owners) findOwnersForPath(path string) string {
+	return o.repo.FindApproverOwnersForPath(normalizePath(path))
+}
+
+// ListOwnersDirs returns the directories with an OWNERS file in root and
+// its subdirectories, regardless of the files of the PR. It returns false
+// if the repo can't list them, see OwnersLister.
+func (o Owners) ListOwnersDirs(root string) ([]string, bool) {
+	switch lister := o.repo.(type) {
+	case OwnersLister:
+		return lister.ListOwnersDirs(normalizePath(root)), true
+	case optionalOwnersLister:
+		return lister.ListOwnersDirs(normalizePath(root))
+	}
+	return nil, false
+}
+
+// OwnersProblem is a problem found in an OWNERS file by Validate.
+type OwnersProblem struct {
+	// OwnersFile is the OWNERS key of the file, see GetOwnersSet.
+	OwnersFile string
+	// Problem describes what is wrong, e.g. "empty approvers".
+	Problem string
+	// OwnersFileName is the name of the file, if the repo knows it (see
+	// OwnersFileNamer), OWNERS otherwise.
+	OwnersFileName string
+}
+
+func (p OwnersProblem) String() string {
+	dir, _ := features.SplitOwnersKey(p.OwnersFile)
+	return fmt.Sprintf("%s: %s", ownersFilePath(dir, p.OwnersFileName), p.Problem)
+}
+
+// Validate checks the OWNERS files of the PR before suggesting approvers.
+// It reports the OWNERS files that failed to parse, if the repo knows
+// them (see ParseErrorReporter), then those of GetOwnersSet listing no
+// approvers or names that aren't aliases nor logins. It returns no
+// problems if all of them are fine.
+func (o Owners) Validate() []OwnersProblem {
+	problems := []OwnersProblem{}
+	namer, canName := o.repo.(OwnersFileNamer)
+	addProblem := func(key, problem string) {
+		p := OwnersProblem{OwnersFile: key, Problem: problem}
+		if canName {
+			p.OwnersFileName = namer.OwnersFileName(key)
+		}
+		problems = append(problems, p)
+	}
+	if reporter, ok := o.repo.(ParseErrorReporter); ok {
+		parseErrors := map[string]error{}
+		for _, fn := range o.filenames {
+			for dir, err := range reporter.ParseErrors(normalizePath(fn)) {
+				parseErrors[dir] = err
+			}
+		}
+		dirs := []string{}
+		for dir := range parseErrors {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			addProblem(dir, fmt.Sprintf("parse error: %v", parseErrors[dir]))
+		}
+	}
+	validator, canValidate := o.repo.(aliasValidator)
+	for _, fn := range o.GetOwnersSet().List() {
+		if o.repo.Approvers(fn).Len() == 0 {
+			addProblem(fn, "empty approvers")
+		}
+		if !canValidate {
+			continue
+		}
+		for _, name := range validator.Validate(fn) {
+			addProblem(fn, fmt.Sprintf("unresolved alias %q", name))
+		}
+	}
+	return problems
+}
+
+// OwnersForFiles returns the OWNERS file governing each file of the PR.
+// Unlike GetOwnersSet, OWNERS files aren't merged into their parents.
+func (o Owners) OwnersForFiles() map[string]string {
+	ownersForFiles := map[string]string{}
+	for _, fn := range o.filenames {
+		ownersForFiles[fn] = o.findOwnersForPath(fn)
+	}
+	return ownersForFiles
+}
+
+// OwnersLevel is an OWNERS file contributing approvers to a path, see
+// ApproverChain.
+type OwnersLevel struct {
+	// Dir is the OWNERS key, see FindApproverOwnersForPath.
+	Dir       string
+	Approvers sets.String
+}
+
+// ApproverChain returns the OWNERS files whose approvers can approve path,
+// from the closest one to the root, stopping at the first one with the
+// no_parent_owners option. OWNERS listing no approvers are skipped.
+func (o Owners) ApproverChain(path string) []OwnersLevel {
+	chain := []OwnersLevel{}
+	addLevel := func(key string) {
+		if approvers := o.repo.LeafApprovers(key); approvers.Len() != 0 {
+			chain = append(chain, OwnersLevel{Dir: key, Approvers: approvers})
+		}
+	}
+
+	dir, filter := features.SplitOwnersKey(o.findOwnersForPath(path))
+	if filter != "" {
+		// The approvers of the filter are added to the ones of its
+		// OWNERS file.
+		addLevel(features.FilteredOwnersKey(dir, filter))
+	}
+	for ; ; dir = parentDir(dir) {
+		addLevel(dir)
+		if dir == "" || o.repo.IsNoParentOwners(dir) {
+			break
+		}
+	}
+	return chain
+}
+
+// Shuffles the potential approvers so that we don't always suggest the same people
+func (o Owners) GetShuffledApprovers() []string {
+	shuffled := o.shuffle(o.withoutExcluded(o.GetAllPotentialApprovers()))
+	if o.stats != nil && len(shuffled) > o.stats.PoolSize {
+		o.stats.PoolSize = len(shuffled)
+	}
+	return shuffled
+}
+
+// withoutExcluded returns people without the excluded people, ignoring
+// case, and without the unavailable people.
+func (o Owners) withoutExcluded(people []string) []string {
+	if o.excluded.Len() == 0 && o.availableFunc == nil {
+		return people
+	}
+	kept := []string{}
+	for _, person := range people {
+		if IntersectSetsCase(sets.NewString(person), o.excluded).Len() != 0 {
+			continue
+		}
+		if o.availableFunc != nil && !o.availableFunc(person) {
+			continue
+		}
+		kept = append(kept, person)
+	}
+	return kept
+}
+
+// shuffle returns a copy of people in a random order based on the seed
+func (o Owners) shuffle(people []string) []string {
+	order := rand.New(rand.NewSource(o.seed)).Perm(len(people))
+	shuffled := make([]string, 0, len(people))
+	for _, i := range order {
+		shuffled = append(shuffled, people[i])
+	}
+	return shuffled
+}
+
+// isSubdir returns true if dir is located below parent. The comparison is
+// done on path segments so that /apps is not considered to be in /app.
+func isSubdir(parent, dir string) bool {
+	if parent == "" {
+		return dir != ""
+	}
+	return strings.HasPrefix(dir, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// dirKey returns the key used to sort directories so that every directory is
+// immediately followed by its subdirectories.
+func dirKey(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return strings.TrimSuffix(dir, "/") + "/"
+}
+
+type byDirKey []string
+
+func (d byDirKey) Len() int      { return len(d) }
+func (d byDirKey) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d byDirKey) Less(i, j int) bool {
+	if ki, kj := dirKey(d[i]), dirKey(d[j]); ki != kj {
+		return ki < kj
+	}
+	return d[i] < d[j]
+}
+
+// parentDir returns the parent directory of dir, using "" for the root.
+func parentDir(dir string) string {
+	parent := filepath.Dir(strings.TrimSuffix(dir, "/"))
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
+
+// inheritsFrom returns true if the approvers of parent can approve dir, that
+// is if no directory between them sets no_parent_owners.
+func inheritsFrom(parent, dir string, noParentOwners func(string) bool) bool {
+	if noParentOwners == nil {
+		return true
+	}
+	for d := dir; isSubdir(parent, d); d = parentDir(d) {
+		if noParentOwners(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeSubdirs takes a list of directories as an input and returns a set of directories with all
+// subdirectories removed.  E.g. [/a,/a/b/c,/d/e,/d/e/f] -> [/a, /d/e]
+// Subdirectories that don't inherit from their parent (see noParentOwners) are kept.
+// The directories are normalized first, see normalizeOwnersKey.
+func removeSubdirs(dirList []string, noParentOwners func(string) bool) sets.String {
+	normalized := sets.NewString()
+	for _, dir := range dirList {
+		normalized.Insert(normalizeOwnersKey(dir))
+	}
+	sorted := normalized.List()
+	sort.Sort(byDirKey(sorted))
+
+	finalSet := sets.NewString()
+	// kept holds the kept ancestors of the current directory, closest last.
+	kept := []string{}
+	for _, dir := range sorted {
+		// Subdirectories sort right after their parent, so anything
+		// that isn't a parent of dir won't be a parent of the
+		// following directories either.
+		for len(kept) > 0 && !isSubdir(kept[len(kept)-1], dir) {
+			kept = kept[:len(kept)-1]
+		}
+		if len(kept) > 0 && inheritsFrom(kept[len(kept)-1], dir, noParentOwners) {
+			continue
+		}
+		finalSet.Insert(dir)
+		kept = append(kept, dir)
+	}
+	return finalSet
+}
+
+// How is the way an approver approved a PR.
+type How string
+
+const (
+	// HowLGTM is an approval given with /lgtm.
+	HowLGTM How = "LGTM"
+	// HowApproved is an approval given with /approve.
+	HowApproved How = "Approved"
+	// HowReviewApproved is an approval given with a GitHub review.
+	HowReviewApproved How = "Approved via review"
+	// HowAuthorSelfApproved is the implicit approval of the PR author.
+	HowAuthorSelfApproved How = "Author self-approved"
+	// HowDelegated is an approval given by someone on behalf of the
+	// approver, see AddDelegatedApprover.
+	HowDelegated How = "Approved by delegate"
+)
+
+// rank orders the ways to approve by strength: an approval never replaces
+// a stronger one of the same person.
+func (h How) rank() int {
+	switch h {
+	case HowApproved, HowReviewApproved, HowDelegated:
+		return 2
+	case HowAuthorSelfApproved:
+		return 1
+	}
+	return 0
+}
+
+// Approval has the information about each approval on a PR
+type Approval struct {
+	Login     string    // Login of the approver
+	How       How       // How did the approver approved
+	Reference string    // Where did the approver approved
+	SHA       string    // Commit the approver approved, if known
+	Time      time.Time // When the approver approved, zero if unknown
+	// ActingLogin approved on behalf of Login, empty unless the approval
+	// was delegated.
+	ActingLogin string
+
+	// cancelledPaths are the directories for which the approval was
+	// cancelled, see RemoveApproverForPath.
+	cancelledPaths sets.String
+	// scope are the only OWNERS files the approval applies to, see
+	// AddApproverForFile. A nil scope means every OWNERS file.
+	scope sets.String
+}
+
+// uncancelled returns the approval without the cancelled paths that
+// contain the given OWNERS file.
+func (a Approval) uncancelled(ownersFile string) Approval {
+	if a.cancelledPaths.Len() == 0 {
+		return a
+	}
+	cancelled := sets.NewString()
+	for path := range a.cancelledPaths {
+		if ownersFile != path && !isSubdir(path, ownersFile) {
+			cancelled.Insert(path)
+		}
+	}
+	a.cancelledPaths = cancelled
+	return a
+}
+
+// covers returns true if the approval applies to the given OWNERS file.
+func (a Approval) covers(ownersFile string) bool {
+	if a.scope != nil && !a.scope.Has(ownersFile) {
+		return false
+	}
+	for path := range a.cancelledPaths {
+		if ownersFile == path || isSubdir(path, ownersFile) {
+			return false
+		}
+	}
+	return true
+}
+
+// isURL returns true if reference is an absolute http(s) URL.
+func isURL(reference string) bool {
+	u, err := url.Parse(reference)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// CommentReference returns the permalink of the comment commentID of the
+// PR prNumber, the preferred reference of approvals given in comments.
+func CommentReference(org, project string, prNumber, commentID int) string {
+	return fmt.Sprintf("%s/%s/%s/pull/%d#issuecomment-%d", defaultBaseURL, org, project, prNumber, commentID)
+}
+
+// title describes how the approval was given.
+func (a Approval) title() string {
+	if a.ActingLogin != "" {
+		return fmt.Sprintf("%s %s", a.How, a.ActingLogin)
+	}
+	return string(a.How)
+}
+
+// String creates a link for the approval. Use `Login` if you just want the name.
+// The login is rendered without a link if the reference isn't a URL.
+func (a Approval) String() string {
+	if !isURL(a.Reference) {
+		return fmt.Sprintf("*%s*", a.Login)
+	}
+	return fmt.Sprintf(
+		`*<a href="%s" title="%s">%s</a>*`,
+		html.EscapeString(a.Reference),
+		html.EscapeString(a.title()),
+		a.Login,
+	)
+}
+
+type Approvers struct {
+	owners    Owners
+	approvers map[string]Approval
+	assignees sets.String
+	// holds are the people who asked not to merge the PR yet, see
+	// IsBlocked.
+	holds sets.String
+	// labels are the labels of the PR, nil if unknown, see AddLabels.
+	labels sets.String
+	// pinned are the previously posted suggestions, see
+	// SetPinnedSuggestions.
+	pinned sets.String
+	// ccs caches the result of GetCCs until the approvers or assignees
+	// change. It is shared by the copies of the Approvers, which share
+	// their approvers and assignees too, so it is guarded by mu.
+	ccs *ccsCache
+	// stats is passed to the suggestion owners, see GetCCsWithStats.
+	stats *SuggestionStats
+	// mu guards the methods adding and removing approvals, assignees and
+	// holds, so that comments can be processed concurrently. Reading
+	// while they're modified isn't safe.
+	mu *sync.Mutex
+
+	// SuggestReviewers adds the suggested reviewers to the message.
+	SuggestReviewers bool
+	// BaseURL is the github host used in links, e.g. for GitHub
+	// Enterprise. Defaults to https://github.com.
+	BaseURL string
+	// OwnersFileName is the name of the ownership files linked in the
+	// message. Defaults to the name given by the repo if it is an
+	// OwnersFileNamer, or "OWNERS".
+	OwnersFileName string
+	// LoadFunc returns the current review load of a person. If set,
+	// suggestions favor people with a lower load.
+	LoadFunc func(login string) int
+	// ActiveFunc returns false for people who are inactive. If set,
+	// suggestions for an OWNERS file whose leaf approvers are all
+	// inactive fall back to the approvers of the parent OWNERS files.
+	ActiveFunc func(login string) bool
+	// MinimizeSpread suggests the smallest set of approvers belonging to
+	// the fewest teams, see GetMinimalSpreadApprovers.
+	MinimizeSpread bool
+	// ListEligibleApprovers lists the people who can approve each
+	// unapproved file in the message.
+	ListEligibleApprovers bool
+	// ExcludedApprovers are never suggested, e.g. the author of the PR
+	// or bots. Files only they can approve stay unapproved.
+	ExcludedApprovers sets.String
+	// AvailableFunc returns false for people who are unavailable, e.g. on
+	// vacation. If set, they are never suggested, but their approvals
+	// still count.
+	AvailableFunc func(login string) bool
+	// TieBreak returns true if a should be suggested rather than b when
+	// they'd approve as many files, e.g. to prefer the least recently
+	// assigned. By default the first one in the shuffled approvers wins.
+	TieBreak func(a, b string) bool
+	// RootCoveragePenalty is how much less an OWNERS file counts, when
+	// picking which assignees to keep, if the assignee would only approve
+	// it as an approver of a parent OWNERS file. Between 0 and 1, it
+	// keeps leaf approvers rather than a root approver covering more
+	// files, unless the root approver is needed. 0 doesn't penalize root
+	// approvers. It has no effect with MinimizeSpread.
+	RootCoveragePenalty float64
+	// CooldownFunc returns true for people who were recently suggested
+	// and haven't responded yet. If set, they are only suggested for the
+	// files that nobody else can approve. It has no effect with
+	// MinimizeSpread.
+	CooldownFunc func(login string) bool
+	// MaxSuggested caps the number of suggested approvers, keeping the
+	// ones covering the most unapproved files. 0 means no limit.
+	MaxSuggested int
+	// PreferAssignees seeds the suggestions with the assignees that are
+	// leaf approvers, so that GetCCs only suggests new people for the
+	// files they don't cover.
+	PreferAssignees bool
+	// SelfApprovalAllowed lets ApplyAuthorSelfApproval credit the PR
+	// author as an approver.
+	SelfApprovalAllowed bool
+	// MinDistinctApprovers is the number of different people who must
+	// approve the PR for IsApproved, e.g. 2 for a two-person rule, on
+	// top of every OWNERS file being approved. 0 means no minimum.
+	MinDistinctApprovers int
+	// InvalidateStale makes InvalidateStaleApprovals drop the approvals
+	// given on a commit other than the head of the PR.
+	InvalidateStale bool
+	// CaseSensitive compares the approvers with the OWNERS files in a
+	// case-sensitive way, for repos whose identities aren't github
+	// logins. Github logins are case-insensitive, the default. People
+	// differing by case then approve separately, so it must be set
+	// before adding approvals.
+	CaseSensitive bool
+	// ShowSingleApprover mentions in the message when a single approver
+	// covers all the files, see SingleApproverCovered.
+	ShowSingleApprover bool
+	// BranchResolver returns the default branch of org/project, linked
+	// when no branch is given. Links point to master if it is nil.
+	BranchResolver func(org, project string) string
+}
+
+// ccsCache holds the result of GetCCs, see Approvers.ccs.
+type ccsCache struct {
+	valid bool
+	ccs   []string
+}
+
+// invalidateCCs drops the cached GetCCs result, after the approvers or
+// assignees changed. mu must be held.
+func (ap *Approvers) invalidateCCs() {
+	if ap.ccs != nil {
+		ap.ccs.valid = false
+	}
+}
+
+// intersectApprovers returns the current approvers found in potential,
+// ignoring case unless CaseSensitive is set.
+func (ap Approvers) intersectApprovers(current, potential sets.String) sets.String {
+	if ap.CaseSensitive {
+		return current.Intersection(potential)
+	}
+	return IntersectSetsCase(current, potential)
+}
+
+// IntersectSetsCase runs the intersection between to sets.String in a
 // case-insensitive way. It returns the name with the case of "one".
 func IntersectSetsCase(one, other sets.String) sets.String {
 	lower := sets.NewString()
@@ -256,146 +1753,1053 @@ func IntersectSetsCase(one, other sets.String) sets.String {
 			intersection.Insert(item)
 		}
 	}
-	return intersection
+	return intersection
+}
+
+// NewApprovers create a new "Approvers" with no approval.
+// Approvals are keyed by lowercase login, so that the same person approving
+// with a different casing is only counted once, see approvalKey.
+func NewApprovers(owners Owners) Approvers {
+	return Approvers{
+		owners:    owners,
+		approvers: map[string]Approval{},
+		assignees: sets.NewString(),
+		holds:     sets.NewString(),
+		ccs:       &ccsCache{},
+		mu:        &sync.Mutex{},
+	}
+}
+
+// approvalKey returns the key of the approval of login: its lowercase
+// login, or login itself if CaseSensitive is set, as people differing by
+// case are then different approvers.
+func (ap Approvers) approvalKey(login string) string {
+	if ap.CaseSensitive {
+		return login
+	}
+	return strings.ToLower(login)
+}
+
+// lock locks mu, if set.
+func (ap *Approvers) lock() {
+	if ap.mu != nil {
+		ap.mu.Lock()
+	}
+}
+
+func (ap *Approvers) unlock() {
+	if ap.mu != nil {
+		ap.mu.Unlock()
+	}
+}
+
+// AddLGTMer adds a new LGTM Approver. sha is the commit that was
+// approved, or empty if unknown.
+func (ap *Approvers) AddLGTMer(login, reference, sha string) {
+	ap.AddLGTMerAt(login, reference, sha, time.Time{})
+}
+
+// AddLGTMerAt is AddLGTMer for an LGTM given at the given time.
+func (ap *Approvers) AddLGTMerAt(login, reference, sha string, at time.Time) {
+	ap.addApproval(Approval{
+		Login:     login,
+		How:       HowLGTM,
+		Reference: reference,
+		SHA:       sha,
+		Time:      at,
+	})
+}
+
+// addApproval stores the approval, unless the same person already gave a
+// stronger approval, e.g. a /lgtm doesn't downgrade an /approve.
+func (ap *Approvers) addApproval(approval Approval) {
+	ap.lock()
+	defer ap.unlock()
+	ap.putApproval(approval)
+}
+
+// putApproval is addApproval for callers holding the lock.
+func (ap *Approvers) putApproval(approval Approval) {
+	key := ap.approvalKey(approval.Login)
+	if existing, ok := ap.approvers[key]; ok && existing.How.rank() > approval.How.rank() {
+		return
+	}
+	ap.approvers[key] = approval
+	ap.invalidateCCs()
+}
+
+// AddApprover adds a new Approver. sha is the commit that was approved,
+// or empty if unknown.
+func (ap *Approvers) AddApprover(login, reference, sha string) {
+	ap.AddApproverAt(login, reference, sha, time.Time{})
+}
+
+// AddApproverAt is AddApprover for an approval given at the given time.
+func (ap *Approvers) AddApproverAt(login, reference, sha string, at time.Time) {
+	ap.addApproval(Approval{
+		Login:     login,
+		How:       HowApproved,
+		Reference: reference,
+		SHA:       sha,
+		Time:      at,
+	})
+}
+
+// AddReviewApprover adds an approval given with a GitHub review.
+// reviewURL is the link to the review.
+func (ap *Approvers) AddReviewApprover(login, reviewURL string) {
+	ap.addApproval(Approval{
+		Login:     login,
+		How:       HowReviewApproved,
+		Reference: reviewURL,
+	})
+}
+
+// AddDelegatedApprover adds an approval given by login on behalf of
+// onBehalfOf. The approval is credited to onBehalfOf, so it approves the
+// files onBehalfOf owns even if login doesn't.
+func (ap *Approvers) AddDelegatedApprover(login, onBehalfOf, reference string) {
+	ap.addApproval(Approval{
+		Login:       onBehalfOf,
+		How:         HowDelegated,
+		Reference:   reference,
+		ActingLogin: login,
+	})
+}
+
+// AddSAuthorSelfApprover adds the author self approval
+func (ap *Approvers) AddAuthorSelfApprover(login, reference string) {
+	ap.AddAuthorSelfApproverAt(login, reference, time.Time{})
+}
+
+// AddAuthorSelfApproverAt is AddAuthorSelfApprover for a self approval
+// given at the given time, e.g. when the PR was opened.
+func (ap *Approvers) AddAuthorSelfApproverAt(login, reference string, at time.Time) {
+	ap.addApproval(Approval{
+		Login:     login,
+		How:       HowAuthorSelfApproved,
+		Reference: reference,
+		Time:      at,
+	})
+}
+
+// ApplyAuthorSelfApproval adds the author self approval if the policy
+// allows it and the author is an approver of one of the OWNERS files.
+// It returns true if the approval was added.
+func (ap *Approvers) ApplyAuthorSelfApproval(login, reference string) bool {
+	if !ap.SelfApprovalAllowed || !ap.isPotentialApprover(login) {
+		return false
+	}
+	ap.AddAuthorSelfApprover(login, reference)
+	return true
+}
+
+// isPotentialApprover returns true if login can approve at least one of the
+// OWNERS files.
+func (ap Approvers) isPotentialApprover(login string) bool {
+	for _, approvers := range ap.owners.GetApprovers() {
+		if IntersectSetsCase(sets.NewString(login), approvers).Len() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidateStaleApprovals removes the approvals given on a commit other
+// than headSHA, if InvalidateStale is set. Approvals with no known commit
+// are kept.
+func (ap *Approvers) InvalidateStaleApprovals(headSHA string) {
+	if !ap.InvalidateStale {
+		return
+	}
+	ap.lock()
+	defer ap.unlock()
+	for login, approval := range ap.approvers {
+		if approval.SHA != "" && approval.SHA != headSHA {
+			delete(ap.approvers, login)
+			ap.invalidateCCs()
+		}
+	}
+}
+
+// AddApproverForFile adds an approval given in a review thread on
+// filePath. It only approves the OWNERS file of filePath, not the other
+// OWNERS files of the PR. It doesn't replace an approval login already
+// gave for the whole PR.
+func (ap *Approvers) AddApproverForFile(login, filePath, reference string) {
+	ownersFile := ap.owners.findOwnersForPath(filePath)
+	scope := sets.NewString(ownersFile)
+	ap.lock()
+	defer ap.unlock()
+	key := ap.approvalKey(login)
+	if existing, ok := ap.approvers[key]; ok {
+		if existing.scope == nil {
+			// Keep the approval of the whole PR, e.g. an LGTM, only
+			// lifting its cancellation for the file.
+			ap.approvers[key] = existing.uncancelled(ownersFile)
+			ap.invalidateCCs()
+			return
+		}
+		scope = scope.Union(existing.scope)
+	}
+	ap.putApproval(Approval{
+		Login:     login,
+		How:       HowApproved,
+		Reference: reference,
+		scope:     scope,
+	})
+}
+
+// RemoveApproverForPath cancels the approval of login for the OWNERS
+// files in path and its subdirectories only. The approval still counts for
+// the other OWNERS files, until the approver approves again.
+func (ap *Approvers) RemoveApproverForPath(login, path string) {
+	ap.lock()
+	defer ap.unlock()
+	key := ap.approvalKey(login)
+	approval, ok := ap.approvers[key]
+	if !ok {
+		return
+	}
+	cancelled := sets.NewString(strings.Trim(path, "/"))
+	if approval.cancelledPaths != nil {
+		cancelled = cancelled.Union(approval.cancelledPaths)
+	}
+	approval.cancelledPaths = cancelled
+	ap.approvers[key] = approval
+	ap.invalidateCCs()
+}
+
+// RemoveApprover removes an approver from the list.
+func (ap *Approvers) RemoveApprover(login string) {
+	ap.lock()
+	defer ap.unlock()
+	delete(ap.approvers, ap.approvalKey(login))
+	ap.invalidateCCs()
+}
+
+// AddHold records that login asked not to merge the PR yet, see
+// IsBlocked.
+func (ap *Approvers) AddHold(login string) {
+	ap.lock()
+	defer ap.unlock()
+	ap.holds.Insert(login)
+}
+
+// RemoveHold removes the hold of login, ignoring case.
+func (ap *Approvers) RemoveHold(login string) {
+	ap.lock()
+	defer ap.unlock()
+	for hold := range ap.holds {
+		if strings.EqualFold(hold, login) {
+			ap.holds.Delete(hold)
+		}
+	}
+}
+
+// IsBlocked returns true if someone put the PR on hold. A blocked PR
+// shouldn't merge, even if IsApproved is true.
+func (ap Approvers) IsBlocked() bool {
+	return ap.holds.Len() != 0
+}
+
+// AddAssignees adds assignees to the list
+func (ap *Approvers) AddAssignees(logins ...string) {
+	ap.lock()
+	defer ap.unlock()
+	ap.assignees.Insert(logins...)
+	ap.invalidateCCs()
+}
+
+// AddLabels adds labels of the PR. Once called, even without labels, the
+// labels required by the OWNERS files that the PR lacks are rendered, see
+// MissingRequiredLabels. The labels applied automatically, see
+// GetRequiredLabels, aren't.
+func (ap *Approvers) AddLabels(labels ...string) {
+	ap.lock()
+	defer ap.unlock()
+	if ap.labels == nil {
+		ap.labels = sets.NewString()
+	}
+	ap.labels.Insert(labels...)
+}
+
+// MissingRequiredLabels returns the labels required by the OWNERS files of
+// the PR that aren't in current, see RequiredLabeler.
+func (ap Approvers) MissingRequiredLabels(current sets.String) sets.String {
+	missing := sets.NewString()
+	for fn := range ap.owners.GetOwnersSet() {
+		missing = missing.Union(ap.owners.requiredLabels(fn).Difference(current))
+	}
+	return missing
+}
+
+// GetCurrentApproversSet returns the set of approvers (login only)
+func (ap Approvers) GetCurrentApproversSet() sets.String {
+	currentApprovers := sets.NewString()
+
+	for _, approval := range ap.approvers {
+		currentApprovers.Insert(approval.Login)
+	}
+
+	return currentApprovers
+}
+
+// currentApproversFor returns the current approvers whose approval applies
+// to the given OWNERS file.
+func (ap Approvers) currentApproversFor(ownersFile string) sets.String {
+	currentApprovers := sets.NewString()
+	for _, approval := range ap.approvers {
+		if approval.covers(ownersFile) {
+			currentApprovers.Insert(approval.Login)
+		}
+	}
+	return currentApprovers
+}
+
+// GetFilesApprovers returns a map from files -> list of current approvers.
+func (ap Approvers) GetFilesApprovers() map[string]sets.String {
+	filesApprovers := map[string]sets.String{}
+
+	for fn, potentialApprovers := range ap.owners.GetApprovers() {
+		currentApprovers := ap.currentApproversFor(fn)
+		// The order of parameter matters here:
+		// - currentApprovers is the list of github handle that have approved
+		// - potentialApprovers is the list of handle in OWNERSa
+		// files that can approve each file.
+		//
+		// We want to keep the syntax of the github handle
+		// rather than the potential mis-cased username found in
+		// the OWNERS file, that's why it's the first parameter.
+		filesApprovers[fn] = ap.intersectApprovers(currentApprovers, potentialApprovers)
+	}
+
+	return filesApprovers
+}
+
+// ApprovalsByFile returns a map from files -> approvals of the current
+// approvers of the file, sorted by login, e.g. to audit how and where
+// each OWNERS file was approved.
+func (ap Approvers) ApprovalsByFile() map[string][]Approval {
+	approvalsByFile := map[string][]Approval{}
+	for fn, approvers := range ap.GetFilesApprovers() {
+		approvals := []Approval{}
+		for _, approver := range approvers.List() {
+			approvals = append(approvals, ap.approvers[ap.approvalKey(approver)])
+		}
+		approvalsByFile[fn] = approvals
+	}
+	return approvalsByFile
+}
+
+// ApproversForFile returns the potential approvers of the OWNERS file
+// covering the given path.
+func (ap Approvers) ApproversForFile(path string) sets.String {
+	return ap.owners.repo.Approvers(ap.owners.findOwnersForPath(path))
+}
+
+// IsFileApproved returns true if enough of the current approvers can
+// approve the given path.
+func (ap Approvers) IsFileApproved(path string) bool {
+	ownersFile := ap.owners.findOwnersForPath(path)
+	required := ap.owners.requiredApprovals(ownersFile)
+	if ap.MissingRequiredApprovers(ownersFile).Len() != 0 {
+		return false
+	}
+	if approvers, ok := ap.GetFilesApprovers()[ownersFile]; ok {
+		return len(approvers) >= required
+	}
+	// The OWNERS file was merged into one of its parents, check it
+	// directly.
+	return ap.intersectApprovers(ap.currentApproversFor(ownersFile), ap.ApproversForFile(path)).Len() >= required
+}
+
+// UnapprovedFiles returns owners files that still need approval, i.e.
+// that have fewer approvers than their required approvals, or whose
+// required approvers haven't all approved.
+func (ap Approvers) UnapprovedFiles() sets.String {
+	if ap.approvedByRoot() {
+		return sets.NewString()
+	}
+	unapproved := sets.NewString()
+	for fn, approvers := range ap.GetFilesApprovers() {
+		if len(approvers) < ap.owners.requiredApprovals(fn) || ap.MissingRequiredApprovers(fn).Len() != 0 {
+			unapproved.Insert(fn)
+		}
+	}
+	return unapproved
+}
+
+// approvedByRoot returns true if an approver of the root OWNERS file
+// approved the whole PR and can approve every OWNERS file alone, so that
+// they don't need to be checked one by one.
+func (ap Approvers) approvedByRoot() bool {
+	rootApprovers, required := ap.owners.rootCover()
+	required = required || len(ap.owners.extraRequired) != 0
+	if rootApprovers.Len() == 0 || (required && !ap.owners.ignoreRequiredApprovers) {
+		return false
+	}
+	for _, approval := range ap.approvers {
+		if approval.scope != nil || approval.cancelledPaths.Len() != 0 {
+			continue
+		}
+		if ap.intersectApprovers(sets.NewString(approval.Login), rootApprovers).Len() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingRequiredApprovers returns the required approvers of the given
+// OWNERS file who haven't approved it yet.
+func (ap Approvers) MissingRequiredApprovers(ownersFile string) sets.String {
+	if ap.owners.ignoreRequiredApprovers {
+		return sets.NewString()
+	}
+	required := ap.owners.requiredApprovers(ownersFile).Union(ap.owners.extraRequired[ownersFile])
+	return required.Difference(ap.intersectApprovers(required, ap.currentApproversFor(ownersFile)))
+}
+
+// RequireExtraApproverForPath makes login a required approver of the
+// OWNERS file covering path, for this PR only, e.g. when the author asks
+// for a stricter review of a sensitive file. The file stays unapproved
+// until login approves it, see MissingRequiredApprovers. If the OWNERS file
+// of path is approved through a parent OWNERS file, the parent requires
+// login.
+func (ap *Approvers) RequireExtraApproverForPath(path, login string) {
+	ownersFile := ap.owners.coveringOwnersFile(ap.owners.findOwnersForPath(path))
+	ap.lock()
+	defer ap.unlock()
+	extraRequired := map[string]sets.String{}
+	for fn, people := range ap.owners.extraRequired {
+		extraRequired[fn] = people
+	}
+	extraRequired[ownersFile] = sets.NewString(login).Union(extraRequired[ownersFile])
+	ap.owners.extraRequired = extraRequired
+	ap.invalidateCCs()
+}
+
+// FilesCoveredBy returns the unapproved OWNERS files that would be
+// approved if login approved the PR. Logins are compared
+// case-insensitively.
+func (ap Approvers) FilesCoveredBy(login string) sets.String {
+	covered := sets.NewString()
+	for approver, files := range ap.owners.GetReverseMap(ap.owners.GetApprovers()) {
+		if strings.EqualFold(approver, login) {
+			covered = covered.Union(files)
+		}
+	}
+	return covered.Intersection(ap.UnapprovedFiles())
+}
+
+// RemainingAfter returns the OWNERS files that would still be unapproved
+// if the given logins approved the PR, without modifying ap.
+func (ap Approvers) RemainingAfter(logins ...string) sets.String {
+	return ap.withApprovers(logins).UnapprovedFiles()
+}
+
+// WouldApprove returns true if the PR would be approved once the given
+// logins approved it, without modifying ap.
+func (ap Approvers) WouldApprove(logins ...string) bool {
+	return ap.RemainingAfter(logins...).Len() == 0
+}
+
+// GetFiles returns owners files with their approval status. Links point
+// to the given branch, or to the default branch if branch is empty (see
+// BranchResolver).
+func (ap Approvers) GetFiles(org, project, branch string) []File {
+	if branch == "" && ap.BranchResolver != nil {
+		branch = ap.BranchResolver(org, project)
+	}
+	allOwnersFiles := []File{}
+	filesApprovers := ap.GetFilesApprovers()
+	unapproved := ap.UnapprovedFiles()
+	unapprovable := ap.UnapprovableFiles()
+	for _, fn := range ap.owners.GetOwnersSet().List() {
+		ownersFileName := ap.ownersFileName(fn)
+		var file File
+		if unapprovable.Has(fn) {
+			file = UnapprovableFile{UnapprovedFile{fn, ap.BaseURL, ownersFileName, org, project, branch, nil}}
+		} else if missing := ap.MissingRequiredApprovers(fn); missing.Len() != 0 {
+			file = RequiredApproversFile{UnapprovedFile{fn, ap.BaseURL, ownersFileName, org, project, branch, nil}, missing}
+		} else if unapproved.Has(fn) {
+			var eligible sets.String
+			if ap.ListEligibleApprovers {
+				eligible = ap.owners.GetApprovers()[fn]
+			}
+			file = UnapprovedFile{fn, ap.BaseURL, ownersFileName, org, project, branch, eligible}
+		} else {
+			file = ApprovedFile{fn, filesApprovers[fn], ap.BaseURL, ownersFileName, org, project, branch}
+		}
+		if ap.labels != nil {
+			if missing := ap.owners.requiredLabels(fn).Difference(ap.labels); missing.Len() != 0 {
+				file = MissingLabelFile{file, missing}
+			}
+		}
+		allOwnersFiles = append(allOwnersFiles, file)
+	}
+
+	return allOwnersFiles
+}
+
+// ownersFileName returns the name of the ownership file of the OWNERS
+// file fn, see OwnersFileName. It is empty for the default name.
+func (ap Approvers) ownersFileName(fn string) string {
+	if ap.OwnersFileName != "" {
+		return ap.OwnersFileName
+	}
+	if namer, ok := ap.owners.repo.(OwnersFileNamer); ok {
+		return namer.OwnersFileName(fn)
+	}
+	return ""
+}
+
+// GetCCs gets the list of suggested approvers for a pull-request.  It
+// now considers current assignees as potential approvers. Here is how
+// it works:
+// - We find suggested approvers from all potential approvers, but
+// remove those that are not useful considering current approvers and
+// assignees. This only uses leave approvers to find approvers the
+// closest to the changes.
+// - We find a subset of suggested approvers from from current
+// approvers, suggested approvers and assignees, but we remove thoses
+// that are not useful considering suggestd approvers and current
+// approvers. This uses the full approvers list, and will result in root
+// approvers to be suggested when they are assigned.
+// We return the union of the two sets: suggested and suggested
+// assignees.
+// The goal of this second step is to only keep the assignees that are
+// the most useful.
+// The result is cached until the approvers or assignees change, so the
+// options must be set before the first call.
+func (ap Approvers) GetCCs() []string {
+	if ap.ccs == nil {
+		return ap.computeCCs()
+	}
+	ap.lock()
+	cached, valid := ap.ccs.ccs, ap.ccs.valid
+	ap.unlock()
+	if valid {
+		return cached
+	}
+	ccs := ap.computeCCs()
+	ap.lock()
+	ap.ccs.ccs = ccs
+	ap.ccs.valid = true
+	ap.unlock()
+	return ccs
+}
+
+// computeCCs is GetCCs without the cache.
+func (ap Approvers) computeCCs() []string {
+	suggested, keepAssignees := ap.getCCs()
+	if ap.PreferAssignees {
+		// The kept assignees are already assigned.
+		return suggested.List()
+	}
+	return suggested.Union(keepAssignees).List()
+}
+
+// MinimalAssignRequest returns the fewest people to assign so that the
+// unapproved files can all be approved, picking from all their approvers
+// rather than only the closest ones like GetCCs. Files that nobody can
+// approve are ignored. It falls back to GetCCs for PRs too large for an
+// exact search or requiring several approvals per file.
+func (ap Approvers) MinimalAssignRequest() []string {
+	if required := ap.missingRequiredApprovers(); required.Len() != 0 {
+		// Required approvers are always needed.
+		return required.Union(sets.NewString(ap.withApprovers(required.List()).MinimalAssignRequest()...)).List()
+	}
+	owners := ap.suggestionOwners()
+	unapproved := ap.UnapprovedFiles()
+	if unapproved.Len() > MinimalApproversThreshold || owners.requiresSeveralApprovals() {
+		return ap.GetCCs()
+	}
+
+	approvers := map[string]sets.String{}
+	for fn, people := range owners.GetApprovers() {
+		if unapproved.Has(fn) {
+			approvers[fn] = people
+		}
+	}
+	reverseMap := owners.GetReverseMap(approvers)
+	pool := sets.NewString()
+	for person := range reverseMap {
+		pool.Insert(person)
+	}
+	people := owners.shuffle(owners.withoutExcluded(pool.List()))
+	coverable := sets.NewString()
+	for _, person := range people {
+		coverable = coverable.Union(reverseMap[person])
+	}
+	return sets.NewString(findMinimalCover(people, reverseMap, coverable, nil, nil)...).List()
+}
+
+// CCExplanation details how GetCCs chose the people to notify.
+type CCExplanation struct {
+	// RandomizedPool are the candidates to suggest, in the order they
+	// are considered.
+	RandomizedPool []string
+	// Suggested are the new approvers suggested to cover the unapproved
+	// files.
+	Suggested sets.String
+	// KeptAssignees are the assignees still useful to approve the PR.
+	KeptAssignees sets.String
+	// CCs is the result of GetCCs, the union of Suggested and
+	// KeptAssignees, unless PreferAssignees is set in which case the
+	// assignees are left out.
+	CCs []string
+}
+
+// GetCCsExplain returns the intermediate results of GetCCs, to
+// understand why someone was or wasn't suggested.
+func (ap Approvers) GetCCsExplain() CCExplanation {
+	suggested, keepAssignees := ap.getCCs()
+	return CCExplanation{
+		RandomizedPool: ap.suggestionOwners().GetShuffledApprovers(),
+		Suggested:      suggested,
+		KeptAssignees:  keepAssignees,
+		CCs:            ap.GetCCs(),
+	}
+}
+
+// SuggestExcluding suggests approvers for the unapproved files other
+// than the people in exclude, e.g. to route around assignees who don't
+// respond. Assignees aren't kept. Files that only excluded leaf approvers
+// can approve escalate to the approvers of their parent OWNERS files.
+func (ap Approvers) SuggestExcluding(exclude sets.String) []string {
+	owners := ap.suggestionOwners()
+	owners.excluded = owners.excluded.Union(exclude)
+	currentApprovers := ap.GetCurrentApproversSet()
+
+	leafReverseMap := owners.GetReverseMap(owners.getSuggestableApprovers())
+	suggested := owners.KeepCoveringApprovers(leafReverseMap, currentApprovers, owners.GetShuffledApprovers())
+	known := currentApprovers.Union(suggested)
+	remaining := owners.temporaryUnapprovedFiles(known)
+	if remaining.Len() == 0 {
+		return suggested.List()
+	}
+
+	escalated := sets.NewString()
+	for _, fn := range remaining.List() {
+		escalated = escalated.Union(owners.GetApprovers()[fn])
+	}
+	fullReverseMap := owners.GetReverseMap(owners.GetApprovers())
+	pool := owners.shuffle(owners.withoutExcluded(escalated.List()))
+	return suggested.Union(owners.KeepCoveringApprovers(fullReverseMap, known, pool)).List()
+}
+
+// SuggestionStats describe the work done to suggest approvers, to
+// detect PRs for which the suggestions are slow.
+type SuggestionStats struct {
+	// Iterations is the number of rounds of the greedy cover of
+	// GetSuggestedApprovers, each of them picking an approver.
+	Iterations int
+	// PoolSize is the number of candidates to suggest from.
+	PoolSize int
+	// Elapsed is the time spent suggesting.
+	Elapsed time.Duration
+}
+
+// GetCCsWithStats is like GetCCs, but also returns the stats of the
+// suggestions. The CCs are always computed, ignoring the cache.
+func (ap Approvers) GetCCsWithStats() ([]string, SuggestionStats) {
+	stats := SuggestionStats{}
+	ap.stats = &stats
+	start := time.Now()
+	ccs := ap.computeCCs()
+	stats.Elapsed = time.Since(start)
+	return ccs, stats
+}
+
+// GetCCsWithReasons returns the people from GetCCs, mapped to the
+// unapproved OWNERS files each of them can approve.
+func (ap Approvers) GetCCsWithReasons() map[string]sets.String {
+	reasons := map[string]sets.String{}
+	for _, cc := range ap.GetCCs() {
+		reasons[cc] = ap.FilesCoveredBy(cc)
+	}
+	return reasons
+}
+
+// GetRedundantAssignees returns the assignees that don't help getting the
+// PR approved, given the current approvers and the suggested approvers.
+func (ap Approvers) GetRedundantAssignees() sets.String {
+	suggested, keepAssignees := ap.getCCs()
+	return ap.assignees.Difference(suggested).Difference(keepAssignees)
+}
+
+// getCCs returns the two sets computed by GetCCs: the suggested
+// approvers and the assignees we keep.
+func (ap Approvers) getCCs() (suggested, keepAssignees sets.String) {
+	if required := ap.missingRequiredApprovers(); required.Len() != 0 {
+		// Required approvers are always needed, find who else is
+		// needed once they approved.
+		suggested, keepAssignees = ap.withApprovers(required.List()).getCCs()
+		return suggested.Union(required.Difference(ap.assignees)), keepAssignees.Union(required.Intersection(ap.assignees))
+	}
+	if ap.pinned.Len() != 0 {
+		// The pinned people still helping are suggested again, find
+		// who else is needed as if they were assigned.
+		kept := ap.keptPinnedSuggestions()
+		suggested, keepAssignees = ap.withPinnedAssigned(kept).getCCs()
+		return suggested.Union(kept), keepAssignees.Difference(kept)
+	}
+	if ap.PreferAssignees {
+		return ap.getCCsPreferringAssignees()
+	}
+	owners := ap.suggestionOwners()
+	if ownersFiles := owners.GetOwnersSet(); ownersFiles.Len() == 1 && owners.loadFunc == nil && owners.tieBreak == nil && owners.cooldownFunc == nil && !owners.minimizeSpread && ap.RootCoveragePenalty == 0 && !ap.hasPartialApprovals() {
+		if fn := ownersFiles.List()[0]; owners.requiredApprovals(fn) == 1 {
+			return ap.getSingleOwnersFileCCs(owners, fn)
+		}
+	}
+	return ap.getCoveringCCs(owners)
 }
 
-// NewApprovers create a new "Approvers" with no approval.
-func NewApprovers(owners Owners) Approvers {
-	return Approvers{
-		owners:    owners,
-		approvers: map[string]Approval{},
-		assignees: sets.NewString(),
+// hasPartialApprovals returns true if an approval only applies to some
+// OWNERS files, i.e. is scoped or partly cancelled, which
+// getSingleOwnersFileCCs doesn't handle.
+func (ap Approvers) hasPartialApprovals() bool {
+	for _, approval := range ap.approvers {
+		if approval.scope != nil || approval.cancelledPaths.Len() != 0 {
+			return true
+		}
 	}
+	return false
 }
 
-// AddLGTMer adds a new LGTM Approver
-func (ap *Approvers) AddLGTMer(login, reference string) {
-	ap.approvers[login] = Approval{
-		Login:     login,
-		How:       "LGTM",
-		Reference: reference,
+// getSingleOwnersFileCCs is getCCs for PRs with a single OWNERS file
+// needing one approval. It returns the same people as getCoveringCCs
+// without building the reverse maps: the first shuffled potential
+// approver if the file isn't approved, and otherwise the first assignee
+// able to approve it.
+func (ap Approvers) getSingleOwnersFileCCs(owners Owners, ownersFile string) (suggested, keepAssignees sets.String) {
+	approvers := owners.GetApprovers()[ownersFile]
+	currentApprovers := ap.GetCurrentApproversSet()
+
+	suggested = sets.NewString()
+	if ap.intersectApprovers(currentApprovers.Union(ap.assignees), approvers).Len() == 0 {
+		// Potential approvers can all approve the file.
+		if randomizedApprovers := owners.GetShuffledApprovers(); len(randomizedApprovers) != 0 {
+			suggested.Insert(randomizedApprovers[0])
+		}
+	}
+
+	keepAssignees = sets.NewString()
+	if suggested.Len() != 0 || ap.intersectApprovers(currentApprovers, approvers).Len() != 0 {
+		return suggested, keepAssignees
+	}
+	for _, person := range owners.withoutExcluded(currentApprovers.Union(ap.assignees).List()) {
+		if approvers.Has(person) {
+			keepAssignees.Insert(person)
+			break
+		}
 	}
+	return suggested, keepAssignees
 }
 
-// AddApprover adds a new Approver
-func (ap *Approvers) AddApprover(login, reference string) {
-	ap.approvers[login] = Approval{
-		Login:     login,
-		How:       "Approved",
-		Reference: reference,
+// getCoveringCCs is getCCs for the general case, running the covering
+// heuristic on the leaf approvers and then on the full approvers.
+func (ap Approvers) getCoveringCCs(owners Owners) (suggested, keepAssignees sets.String) {
+	randomizedApprovers := owners.GetShuffledApprovers()
+
+	currentApprovers := ap.GetCurrentApproversSet()
+	approversAndAssignees := currentApprovers.Union(ap.assignees)
+	leafReverseMap := owners.GetReverseMap(owners.getSuggestableApprovers())
+	suggested = owners.KeepCoveringApprovers(leafReverseMap, approversAndAssignees, randomizedApprovers)
+	if ap.MaxSuggested > 0 && suggested.Len() > ap.MaxSuggested {
+		unapproved := owners.temporaryUnapprovedFiles(approversAndAssignees)
+		suggested = mostCovering(suggested, leafReverseMap, unapproved, ap.MaxSuggested)
 	}
+	approversAndSuggested := currentApprovers.Union(suggested)
+	everyone := approversAndSuggested.Union(ap.assignees)
+	fullReverseMap := owners.GetReverseMap(owners.GetApprovers())
+	fullOwners := owners
+	fullOwners.rootPenalty = ap.RootCoveragePenalty
+	// Keeping an assignee doesn't ping them again.
+	fullOwners.cooldownFunc = nil
+	keepAssignees = fullOwners.KeepCoveringApprovers(fullReverseMap, approversAndSuggested, owners.withoutExcluded(everyone.List()))
+
+	return suggested, keepAssignees
 }
 
-// AddSAuthorSelfApprover adds the author self approval
-func (ap *Approvers) AddAuthorSelfApprover(login, reference string) {
-	ap.approvers[login] = Approval{
-		Login:     login,
-		How:       "Author self-approved",
-		Reference: reference,
+// getCCsPreferringAssignees is getCCs for PreferAssignees: it first keeps
+// the assignees covering unapproved files as leaf approvers, and only
+// suggests approvers from the full shuffled pool for the remaining files.
+func (ap Approvers) getCCsPreferringAssignees() (suggested, keepAssignees sets.String) {
+	owners := ap.suggestionOwners()
+	currentApprovers := ap.GetCurrentApproversSet()
+	leafReverseMap := owners.GetReverseMap(owners.getSuggestableApprovers())
+
+	keepAssignees = sets.NewString()
+	unapproved := owners.temporaryUnapprovedFiles(currentApprovers)
+	for unapproved.Len() != 0 {
+		candidates := owners.withoutExcluded(ap.assignees.Difference(keepAssignees).List())
+		assignee := owners.mostCoveringApprover(candidates, leafReverseMap, unapproved)
+		if assignee == "" {
+			break
+		}
+		keepAssignees.Insert(assignee)
+		unapproved = owners.temporaryUnapprovedFiles(currentApprovers.Union(keepAssignees))
+	}
+	if unapproved.Len() == 0 {
+		return sets.NewString(), keepAssignees
+	}
+
+	known := currentApprovers.Union(keepAssignees)
+	suggested = owners.KeepCoveringApprovers(leafReverseMap, known, owners.GetShuffledApprovers())
+	if ap.MaxSuggested > 0 && suggested.Len() > ap.MaxSuggested {
+		suggested = mostCovering(suggested, leafReverseMap, unapproved, ap.MaxSuggested)
 	}
+	return suggested.Difference(ap.assignees), keepAssignees
 }
 
-// RemoveApprover removes an approver from the list.
-func (ap *Approvers) RemoveApprover(login string) {
-	delete(ap.approvers, login)
+// byCoverage sorts people by decreasing number of covered files, then by
+// login.
+type byCoverage struct {
+	people  []string
+	covered map[string]int
 }
 
-// AddAssignees adds assignees to the list
-func (ap *Approvers) AddAssignees(logins ...string) {
-	ap.assignees.Insert(logins...)
+func (b byCoverage) Len() int      { return len(b.people) }
+func (b byCoverage) Swap(i, j int) { b.people[i], b.people[j] = b.people[j], b.people[i] }
+func (b byCoverage) Less(i, j int) bool {
+	ci, cj := b.covered[b.people[i]], b.covered[b.people[j]]
+	if ci != cj {
+		return ci > cj
+	}
+	return b.people[i] < b.people[j]
 }
 
-// GetCurrentApproversSet returns the set of approvers (login only)
-func (ap Approvers) GetCurrentApproversSet() sets.String {
-	currentApprovers := sets.NewString()
+// mostCovering returns the max people from the given set covering the most
+// files in unapproved.
+func mostCovering(people sets.String, reverseMap map[string]sets.String, unapproved sets.String, max int) sets.String {
+	ranked := byCoverage{people: people.List(), covered: map[string]int{}}
+	for _, person := range ranked.people {
+		ranked.covered[person] = reverseMap[person].Intersection(unapproved).Len()
+	}
+	sort.Sort(ranked)
+	if len(ranked.people) > max {
+		ranked.people = ranked.people[:max]
+	}
+	return sets.NewString(ranked.people...)
+}
 
-	for approver := range ap.approvers {
-		currentApprovers.Insert(approver)
+// missingRequiredApprovers returns the required approvers of every OWNERS
+// file who haven't approved yet and can be suggested.
+func (ap Approvers) missingRequiredApprovers() sets.String {
+	missing := sets.NewString()
+	for fn := range ap.owners.GetOwnersSet() {
+		missing = missing.Union(ap.MissingRequiredApprovers(fn))
 	}
+	return sets.NewString(ap.suggestionOwners().withoutExcluded(missing.List())...)
+}
 
-	return currentApprovers
+// SetPinnedSuggestions pins the approvers suggested by a previous run, so
+// that the suggestions don't change between runs: GetCCs keeps the pinned
+// people who still help approving the PR, and only suggests others for
+// the files they can't approve.
+func (ap *Approvers) SetPinnedSuggestions(logins []string) {
+	ap.lock()
+	defer ap.unlock()
+	ap.pinned = sets.NewString(logins...)
+	ap.invalidateCCs()
 }
 
-// GetFilesApprovers returns a map from files -> list of current approvers.
-func (ap Approvers) GetFilesApprovers() map[string]sets.String {
-	filesApprovers := map[string]sets.String{}
-	currentApprovers := ap.GetCurrentApproversSet()
+// keptPinnedSuggestions returns the pinned people who can still approve
+// files that the current approvers and assignees can't.
+func (ap Approvers) keptPinnedSuggestions() sets.String {
+	owners := ap.suggestionOwners()
+	reverseMap := owners.GetReverseMap(owners.GetApprovers())
+	known := ap.GetCurrentApproversSet().Union(ap.assignees)
+	kept := sets.NewString()
+	unapproved := owners.temporaryUnapprovedFiles(known)
+	for _, login := range owners.withoutExcluded(ap.pinned.List()) {
+		if reverseMap[login].Intersection(unapproved).Len() != 0 {
+			kept.Insert(login)
+			unapproved = owners.temporaryUnapprovedFiles(known.Union(kept))
+		}
+	}
+	return kept
+}
 
-	for fn, potentialApprovers := range ap.owners.GetApprovers() {
-		// The order of parameter matters here:
-		// - currentApprovers is the list of github handle that have approved
-		// - potentialApprovers is the list of handle in OWNERSa
-		// files that can approve each file.
-		//
-		// We want to keep the syntax of the github handle
-		// rather than the potential mis-cased username found in
-		// the OWNERS file, that's why it's the first parameter.
-		filesApprovers[fn] = IntersectSetsCase(currentApprovers, potentialApprovers)
+// withPinnedAssigned returns a copy of ap where the given pinned people
+// are assigned instead of pinned, leaving ap unchanged.
+func (ap Approvers) withPinnedAssigned(pinned sets.String) Approvers {
+	ap.assignees = ap.assignees.Union(pinned)
+	ap.pinned = nil
+	ap.ccs = &ccsCache{}
+	ap.mu = &sync.Mutex{}
+	return ap
+}
+
+// withApprovers returns a copy of ap where the given logins approved,
+// leaving ap unchanged.
+func (ap Approvers) withApprovers(logins []string) Approvers {
+	approvers := map[string]Approval{}
+	for key, approval := range ap.approvers {
+		approvers[key] = approval
+	}
+	ap.approvers = approvers
+	ap.ccs = &ccsCache{}
+	ap.mu = &sync.Mutex{}
+	for _, login := range logins {
+		ap.AddApprover(login, "", "")
 	}
+	return ap
+}
 
-	return filesApprovers
+// suggestionOwners returns the owners configured with the suggestion
+// options of the Approvers.
+func (ap Approvers) suggestionOwners() Owners {
+	owners := ap.owners
+	owners.approvals = ap.approvers
+	owners.caseSensitive = ap.CaseSensitive
+	owners.loadFunc = ap.LoadFunc
+	owners.activeFunc = ap.ActiveFunc
+	owners.minimizeSpread = ap.MinimizeSpread
+	owners.excluded = ap.ExcludedApprovers
+	owners.availableFunc = ap.AvailableFunc
+	owners.tieBreak = ap.TieBreak
+	owners.cooldownFunc = ap.CooldownFunc
+	owners.stats = ap.stats
+	owners.ignoreRequiredApprovers = true
+	return owners
 }
 
-// UnapprovedFiles returns owners files that still need approval
-func (ap Approvers) UnapprovedFiles() sets.String {
-	unapproved := sets.NewString()
-	for fn, approvers := range ap.GetFilesApprovers() {
-		if len(approvers) == 0 {
-			unapproved.Insert(fn)
+// GetSuggestedReviewers returns the list of suggested reviewers for a pull-request.
+func (ap Approvers) GetSuggestedReviewers() []string {
+	return ap.suggestionOwners().GetSuggestedReviewers().List()
+}
+
+// UnapprovableFiles returns the OWNERS files that list fewer approvers than
+// they require, and thus can't be approved without a human intervening.
+func (ap Approvers) UnapprovableFiles() sets.String {
+	unapprovable := sets.NewString()
+	for fn, approvers := range ap.owners.GetApprovers() {
+		if approvers.Len() < ap.owners.requiredApprovals(fn) {
+			unapprovable.Insert(fn)
 		}
 	}
-	return unapproved
+	return unapprovable
 }
 
-// UnapprovedFiles returns owners files that still need approval
-func (ap Approvers) GetFiles(org, project string) []File {
-	allOwnersFiles := []File{}
-	filesApprovers := ap.GetFilesApprovers()
-	for _, fn := range ap.owners.GetOwnersSet().List() {
-		if len(filesApprovers[fn]) == 0 {
-			allOwnersFiles = append(allOwnersFiles, UnapprovedFile{fn, org, project})
-		} else {
-			allOwnersFiles = append(allOwnersFiles, ApprovedFile{fn, filesApprovers[fn], org, project})
+// UnapprovedButOwned returns the unapproved OWNERS files that enough
+// people can approve, i.e. that are waiting on a human rather than
+// unapprovable, see UnapprovableFiles.
+func (ap Approvers) UnapprovedButOwned() sets.String {
+	return ap.UnapprovedFiles().Difference(ap.UnapprovableFiles())
+}
+
+// DeadlockedFiles returns the OWNERS files that can't be approved because
+// the author is one of their approvers but can't self-approve, and the
+// others aren't enough, e.g. when the author is the sole approver. It is
+// empty if SelfApprovalAllowed is set.
+func (ap Approvers) DeadlockedFiles(author string) sets.String {
+	deadlocked := sets.NewString()
+	if ap.SelfApprovalAllowed {
+		return deadlocked
+	}
+	authorSet := sets.NewString(author)
+	for fn, approvers := range ap.owners.GetApprovers() {
+		listedAuthor := IntersectSetsCase(approvers, authorSet)
+		if listedAuthor.Len() == 0 {
+			continue
+		}
+		if approvers.Difference(listedAuthor).Len() < ap.owners.requiredApprovals(fn) {
+			deadlocked.Insert(fn)
 		}
 	}
+	return deadlocked
+}
 
-	return allOwnersFiles
+// ApprovalProgress returns the number of approved OWNERS files, out of
+// the total number of OWNERS files of the PR.
+func (ap Approvers) ApprovalProgress() (approved, total int) {
+	total = ap.owners.GetOwnersSet().Len()
+	return total - ap.UnapprovedFiles().Len(), total
 }
 
-// GetCCs gets the list of suggested approvers for a pull-request.  It
-// now considers current assignees as potential approvers. Here is how
-// it works:
-// - We find suggested approvers from all potential approvers, but
-// remove those that are not useful considering current approvers and
-// assignees. This only uses leave approvers to find approvers the
-// closest to the changes.
-// - We find a subset of suggested approvers from from current
-// approvers, suggested approvers and assignees, but we remove thoses
-// that are not useful considering suggestd approvers and current
-// approvers. This uses the full approvers list, and will result in root
-// approvers to be suggested when they are assigned.
-// We return the union of the two sets: suggested and suggested
-// assignees.
-// The goal of this second step is to only keep the assignees that are
-// the most useful.
-func (ap Approvers) GetCCs() []string {
-	randomizedApprovers := ap.owners.GetShuffledApprovers()
+// IsApproved returns a bool indicating whether or not the PR is approved
+// Approved PRs also need MinDistinctApprovers approvers, if set.
+func (ap Approvers) IsApproved() bool {
+	return ap.UnapprovedFiles().Len() == 0 && ap.MeetsDistinctApproverMinimum(ap.MinDistinctApprovers)
+}
 
-	currentApprovers := ap.GetCurrentApproversSet()
-	approversAndAssignees := currentApprovers.Union(ap.assignees)
-	leafReverseMap := ap.owners.GetReverseMap(ap.owners.GetLeafApprovers())
-	suggested := ap.owners.KeepCoveringApprovers(leafReverseMap, approversAndAssignees, randomizedApprovers)
-	approversAndSuggested := currentApprovers.Union(suggested)
-	everyone := approversAndSuggested.Union(ap.assignees)
-	fullReverseMap := ap.owners.GetReverseMap(ap.owners.GetApprovers())
-	keepAssignees := ap.owners.KeepCoveringApprovers(fullReverseMap, approversAndSuggested, everyone.List())
+// MeetsDistinctApproverMinimum returns true if at least n different
+// approvers of the OWNERS files of the PR approved it. People approving
+// without owning any of the files don't count.
+func (ap Approvers) MeetsDistinctApproverMinimum(n int) bool {
+	return ap.missingDistinctApprovers(n) == 0
+}
 
-	return suggested.Union(keepAssignees).List()
+// missingDistinctApprovers returns how many more approvers of the OWNERS
+// files of the PR must approve it to reach n different approvers.
+func (ap Approvers) missingDistinctApprovers(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	// Look at the OWNERS file of each file rather than GetOwnersSet, so
+	// that leaf approvers count even if a parent OWNERS file governs the
+	// PR too.
+	ownersFiles := sets.NewString()
+	for _, fn := range ap.owners.OwnersForFiles() {
+		ownersFiles.Insert(fn)
+	}
+	distinct := sets.NewString()
+	for fn := range ownersFiles {
+		distinct = distinct.Union(ap.intersectApprovers(ap.currentApproversFor(fn), ap.owners.repo.Approvers(fn)))
+	}
+	if missing := n - distinct.Len(); missing > 0 {
+		return missing
+	}
+	return 0
 }
 
-// IsApproved returns a bool indicating whether or not the PR is approved
-func (ap Approvers) IsApproved() bool {
-	return ap.UnapprovedFiles().Len() == 0
+// LatestApprovalTime returns the time of the most recent approval. It
+// returns false if no approval has a known time.
+func (ap Approvers) LatestApprovalTime() (time.Time, bool) {
+	latest := time.Time{}
+	for _, approval := range ap.approvers {
+		if approval.Time.After(latest) {
+			latest = approval.Time
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// SelfApprovals returns the approvals given implicitly by the author of
+// the PR.
+func (ap Approvers) SelfApprovals() []Approval {
+	approvals := []Approval{}
+	for _, approval := range ap.ListApprovals() {
+		if approval.How == HowAuthorSelfApproved {
+			approvals = append(approvals, approval)
+		}
+	}
+	return approvals
+}
+
+// SingleApproverCovered returns the current approver who alone approves
+// every OWNERS file of the PR, if there is exactly one.
+func (ap Approvers) SingleApproverCovered() (string, bool) {
+	owners := ap.owners
+	owners.caseSensitive = ap.CaseSensitive
+	covering := []string{}
+	for _, approver := range ap.GetCurrentApproversSet().List() {
+		approval := ap.approvers[ap.approvalKey(approver)]
+		if approval.cancelledPaths.Len() != 0 || approval.scope != nil {
+			continue
+		}
+		if owners.temporaryUnapprovedFiles(sets.NewString(approver)).Len() == 0 {
+			covering = append(covering, approver)
+		}
+	}
+	if len(covering) != 1 {
+		return "", false
+	}
+	return covering[0], true
 }
 
 // ListApprovals returns the list of approvals
@@ -403,7 +2807,7 @@ func (ap Approvers) ListApprovals() []Approval {
 	approvals := []Approval{}
 
 	for _, approver := range ap.GetCurrentApproversSet().List() {
-		approvals = append(approvals, ap.approvers[approver])
+		approvals = append(approvals, ap.approvers[ap.approvalKey(approver)])
 	}
 
 	return approvals
@@ -414,44 +2818,257 @@ type File interface {
 }
 
 type ApprovedFile struct {
-	filepath  string
-	approvers sets.String
-	org       string
-	project   string
+	filepath       string
+	approvers      sets.String
+	baseURL        string
+	ownersFileName string
+	org            string
+	project        string
+	branch         string
 }
 
 type UnapprovedFile struct {
-	filepath string
-	org      string
-	project  string
+	filepath       string
+	baseURL        string
+	ownersFileName string
+	org            string
+	project        string
+	branch         string
+	// eligible are the people who can approve the file, listed if set.
+	eligible sets.String
+}
+
+// UnapprovableFile is an OWNERS file with no approvers.
+type UnapprovableFile struct {
+	UnapprovedFile
+}
+
+// RequiredApproversFile is an OWNERS file whose required approvers haven't
+// all approved.
+type RequiredApproversFile struct {
+	UnapprovedFile
+	missing sets.String
+}
+
+// ownersFilePath returns the path of the ownership file in dir, using
+// the default OWNERS name if ownersFileName is empty.
+func ownersFilePath(dir, ownersFileName string) string {
+	if ownersFileName == "" {
+		ownersFileName = defaultOwnersFileName
+	}
+	return filepath.Join(dir, ownersFileName)
+}
+
+// ownersFileLink returns the github link to the given OWNERS file.
+func ownersFileLink(baseURL, org, project, branch, fullOwnersPath string) string {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if branch == "" {
+		branch = defaultBranch
+	}
+	return fmt.Sprintf("%s/%s/%s/blob/%s/%v", strings.TrimSuffix(baseURL, "/"), org, project, branch, fullOwnersPath)
+}
+
+// ownersFileMarkdown returns the markdown link to the OWNERS file of the
+// given key, mentioning the filter pattern if the key has one.
+func ownersFileMarkdown(key, ownersFileName, baseURL, org, project, branch string) string {
+	dir, filter := features.SplitOwnersKey(key)
+	fullOwnersPath := ownersFilePath(dir, ownersFileName)
+	link := ownersFileLink(baseURL, org, project, branch, fullOwnersPath)
+	if filter != "" {
+		return fmt.Sprintf("[%s `%s`](%s)", fullOwnersPath, filter, link)
+	}
+	return fmt.Sprintf("[%s](%s)", fullOwnersPath, link)
 }
 
 func (a ApprovedFile) String() string {
-	fullOwnersPath := filepath.Join(a.filepath, ownersFileName)
-	link := fmt.Sprintf("https://github.com/%s/%s/blob/master/%v", a.org, a.project, fullOwnersPath)
-	return fmt.Sprintf("- ~~[%s](%s)~~ [%v]\n", fullOwnersPath, link, strings.Join(a.approvers.List(), ","))
+	ownersFile := ownersFileMarkdown(a.filepath, a.ownersFileName, a.baseURL, a.org, a.project, a.branch)
+	return fmt.Sprintf("- ~~%s~~ [%v]\n", ownersFile, listApprovers(a.approvers.List()))
+}
+
+// listApprovers joins the approvers, collapsing the list if there are more
+// than MaxListedApprovers.
+func listApprovers(approvers []string) string {
+	if len(approvers) <= MaxListedApprovers {
+		return strings.Join(approvers, ",")
+	}
+	others := len(approvers) - MaxListedApprovers
+	return fmt.Sprintf("%s and %d other%s", strings.Join(approvers[:MaxListedApprovers], ","), others, plural(others))
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
 }
 
 func (ua UnapprovedFile) String() string {
-	fullOwnersPath := filepath.Join(ua.filepath, ownersFileName)
-	link := fmt.Sprintf("https://github.com/%s/%s/blob/master/%v", ua.org, ua.project, fullOwnersPath)
-	return fmt.Sprintf("- **[%s](%s)**\n", fullOwnersPath, link)
+	ownersFile := ownersFileMarkdown(ua.filepath, ua.ownersFileName, ua.baseURL, ua.org, ua.project, ua.branch)
+	if ua.eligible.Len() != 0 {
+		return fmt.Sprintf("- **%s** needs approval from one of [%v]\n", ownersFile, listApprovers(ua.eligible.List()))
+	}
+	return fmt.Sprintf("- **%s**\n", ownersFile)
+}
+
+func (rf RequiredApproversFile) String() string {
+	ownersFile := ownersFileMarkdown(rf.filepath, rf.ownersFileName, rf.baseURL, rf.org, rf.project, rf.branch)
+	return fmt.Sprintf("- **%s** needs approval from required approver%s [%v]\n", ownersFile, plural(rf.missing.Len()), listApprovers(rf.missing.List()))
+}
+
+func (ua UnapprovableFile) String() string {
+	ownersFile := ownersFileMarkdown(ua.filepath, ua.ownersFileName, ua.baseURL, ua.org, ua.project, ua.branch)
+	return fmt.Sprintf("- **%s** :warning: lists no approvers, a human must intervene\n", ownersFile)
+}
+
+// MissingLabelFile is an OWNERS file requiring labels that the PR lacks.
+// It is rendered as File, followed by the missing labels.
+type MissingLabelFile struct {
+	File
+	missing sets.String
+}
+
+func (ml MissingLabelFile) String() string {
+	return fmt.Sprintf("%s  - :warning: requires the label%s %s\n", ml.File.String(), plural(ml.missing.Len()), quoteLabels(ml.missing.List()))
+}
+
+func (ml MissingLabelFile) plainText() string {
+	file := strings.TrimSuffix(ml.File.String(), "\n")
+	if plain, ok := ml.File.(plainTextFile); ok {
+		file = plain.plainText()
+	}
+	return fmt.Sprintf("%s\n  - requires the label%s %s", file, plural(ml.missing.Len()), strings.Join(ml.missing.List(), ", "))
+}
+
+// quoteLabels renders labels as a comma-separated list of code spans.
+func quoteLabels(labels []string) string {
+	quoted := []string{}
+	for _, label := range labels {
+		quoted = append(quoted, "`"+label+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// plainTextFile is implemented by the files that can be rendered without
+// markdown, see GetPlainTextMessage.
+type plainTextFile interface {
+	plainText() string
+}
+
+// ownersFilePlainText is ownersFileMarkdown rendered as "path (url)".
+func ownersFilePlainText(key, ownersFileName, baseURL, org, project, branch string) string {
+	dir, filter := features.SplitOwnersKey(key)
+	fullOwnersPath := ownersFilePath(dir, ownersFileName)
+	link := ownersFileLink(baseURL, org, project, branch, fullOwnersPath)
+	if filter != "" {
+		return fmt.Sprintf("%s %s (%s)", fullOwnersPath, filter, link)
+	}
+	return fmt.Sprintf("%s (%s)", fullOwnersPath, link)
+}
+
+func (a ApprovedFile) plainText() string {
+	ownersFile := ownersFilePlainText(a.filepath, a.ownersFileName, a.baseURL, a.org, a.project, a.branch)
+	return fmt.Sprintf("- %s approved by %v", ownersFile, listApprovers(a.approvers.List()))
+}
+
+func (ua UnapprovedFile) plainText() string {
+	ownersFile := ownersFilePlainText(ua.filepath, ua.ownersFileName, ua.baseURL, ua.org, ua.project, ua.branch)
+	if ua.eligible.Len() != 0 {
+		return fmt.Sprintf("- %s needs approval from one of %v", ownersFile, listApprovers(ua.eligible.List()))
+	}
+	return fmt.Sprintf("- %s needs approval", ownersFile)
+}
+
+func (rf RequiredApproversFile) plainText() string {
+	ownersFile := ownersFilePlainText(rf.filepath, rf.ownersFileName, rf.baseURL, rf.org, rf.project, rf.branch)
+	return fmt.Sprintf("- %s needs approval from required approver%s %v", ownersFile, plural(rf.missing.Len()), listApprovers(rf.missing.List()))
+}
+
+func (ua UnapprovableFile) plainText() string {
+	ownersFile := ownersFilePlainText(ua.filepath, ua.ownersFileName, ua.baseURL, ua.org, ua.project, ua.branch)
+	return fmt.Sprintf("- %s lists no approvers, a human must intervene", ownersFile)
+}
+
+// FileGroup is the OWNERS files of a top-level area of the repo, see
+// GroupFilesByArea.
+type FileGroup struct {
+	// Area is the first segment of the path of the files, or "/" for
+	// the root OWNERS file.
+	Area  string
+	Files []File
+}
+
+// GroupFilesByArea groups files by the first segment of their path,
+// keeping their order and approval status. Groups are sorted by area.
+func GroupFilesByArea(files []File) []FileGroup {
+	groups := []FileGroup{}
+	index := map[string]int{}
+	for _, file := range files {
+		area := fileArea(file)
+		i, ok := index[area]
+		if !ok {
+			i = len(groups)
+			index[area] = i
+			groups = append(groups, FileGroup{Area: area})
+		}
+		groups[i].Files = append(groups[i].Files, file)
+	}
+	sort.Sort(fileGroupsByArea(groups))
+	return groups
+}
+
+type fileGroupsByArea []FileGroup
+
+func (g fileGroupsByArea) Len() int           { return len(g) }
+func (g fileGroupsByArea) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+func (g fileGroupsByArea) Less(i, j int) bool { return g[i].Area < g[j].Area }
+
+// fileArea returns the first segment of the path of the OWNERS file, or
+// "/" for the root.
+func fileArea(file File) string {
+	var key string
+	switch f := file.(type) {
+	case ApprovedFile:
+		key = f.filepath
+	case UnapprovedFile:
+		key = f.filepath
+	case RequiredApproversFile:
+		key = f.filepath
+	case UnapprovableFile:
+		key = f.filepath
+	case MissingLabelFile:
+		return fileArea(f.File)
+	}
+	dir, _ := features.SplitOwnersKey(key)
+	if dir == "" || dir == "." {
+		return "/"
+	}
+	return strings.SplitN(dir, "/", 2)[0]
 }
 
 // GenerateTemplateOrFail takes a template, name and data, and generates
 // the corresping string. nil is returned if it fails. An error is
 // logged.
 func GenerateTemplateOrFail(templ, name string, data interface{}) *string {
+	message, err := GenerateTemplate(templ, name, data)
+	if err != nil {
+		glog.Error(err)
+		return nil
+	}
+	return &message
+}
+
+// GenerateTemplate is like GenerateTemplateOrFail, but returns the error
+// instead of logging it.
+func GenerateTemplate(templ, name string, data interface{}) (string, error) {
 	buf := bytes.NewBufferString("")
 	if messageTempl, err := template.New(name).Parse(templ); err != nil {
-		glog.Errorf("Failed to generate template for %s: %s", name, err)
-		return nil
+		return "", fmt.Errorf("Failed to generate template for %s: %s", name, err)
 	} else if err := messageTempl.Execute(buf, data); err != nil {
-		glog.Errorf("Failed to execute template for %s: %s", name, err)
-		return nil
+		return "", fmt.Errorf("Failed to execute template for %s: %s", name, err)
 	}
-	message := buf.String()
-	return &message
+	return buf.String(), nil
 }
 
 // getMessage returns the comment body that we want the approval-handler to display on PRs
@@ -459,42 +3076,300 @@ func GenerateTemplateOrFail(templ, name string, data interface{}) *string {
 // 	- a list of approvers files (and links) needed to get the PR approved
 // 	- a list of approvers files with strikethroughs that already have an approver's approval
 // 	- a suggested list of people from each OWNERS files that can fully approve the PR
+// 	- a suggested list of reviewers, if SuggestReviewers is set
 // 	- how an approver can indicate their approval
 // 	- how an approver can cancel their approval
-func GetMessage(ap Approvers, org, project string) *string {
-	message := GenerateTemplateOrFail(`This pull-request has been approved by: {{range $index, $approval := .ap.ListApprovals}}{{if $index}}, {{end}}{{$approval}}{{end}}
-{{- if not .ap.IsApproved}}
-We suggest the following additional approver{{if ne 1 (len .ap.GetCCs)}}s{{end}}: {{range $index, $cc := .ap.GetCCs}}{{if $index}}, {{end}}**{{$cc}}**{{end}}
+func GetMessage(ap Approvers, org, project, branch string) *string {
+	return GetMessageWithTemplate(ap, org, project, branch, DefaultMessageTemplate)
+}
 
-Assign the PR to them by writing `+"`/assign {{range $index, $cc := .ap.GetCCs}}{{if $index}} {{end}}@{{$cc}}{{end}}`"+` in a comment when ready.
+// GetMessageE is like GetMessage, but returns the error if the message
+// fails to render, instead of logging it and returning nil.
+func GetMessageE(ap Approvers, org, project, branch string) (string, error) {
+	return GetMessageWithOptionsE(ap, org, project, branch, MessageOptions{})
+}
+
+// DefaultMessageTemplate is the template of the notification body used by
+// GetMessage. It is executed with the ApprovalState of the PR.
+const DefaultMessageTemplate = `This pull-request has been approved by: {{range $index, $approval := .Approvals}}{{if $index}}, {{end}}{{$approval}}{{end}}
+{{- if .SingleApprover}}
+Approved by @{{.SingleApprover}} (covers all files)
+{{- end}}
+{{- if .Holds}}
+On hold by {{range $index, $hold := .Holds}}{{if $index}}, {{end}}**{{$hold}}**{{end}}, this PR must not merge until the hold{{if ne 1 (len .Holds)}}s are{{else}} is{{end}} removed.
+{{- end}}
+{{- if .MissingDistinctApprovers}}
+This PR needs the approval of {{.MissingDistinctApprovers}} more approver{{if ne 1 .MissingDistinctApprovers}}s{{end}} from the OWNERS files, to be approved by {{.MinDistinctApprovers}} different approvers.
+{{- end}}
+{{- if and (not .Approved) (not .OmitSuggestions)}}
+{{- if .SuggestedCCs}}
+We suggest the following additional approver{{if ne 1 (len .SuggestedCCs)}}s{{end}}: {{range $index, $cc := .SuggestedCCs}}{{if $index}}, {{end}}**{{$cc}}**{{end}}
+
+Assign the PR to them by writing ` + "`/assign {{range $index, $cc := .SuggestedCCs}}{{if $index}} {{end}}@{{$cc}}{{end}}`" + ` in a comment when ready.
+{{- end}}
+{{- if .SuggestedReviewers}}
+We suggest the following reviewer{{if ne 1 (len .SuggestedReviewers)}}s{{end}}: {{range $index, $reviewer := .SuggestedReviewers}}{{if $index}}, {{end}}**{{$reviewer}}**{{end}}
+{{- end}}
 {{- end}}
 
-<details {{if not .ap.IsApproved}}open{{end}}>
+<details {{if not .Approved}}open{{end}}>
 Needs approval from an approver in each of these OWNERS Files:
 
-{{range .ap.GetFiles .org .project}}{{.}}{{end}}
+{{if .FileGroups}}{{range .FileGroups}}**{{.Area}}**
+
+{{range .Files}}{{.}}{{end}}
+{{end}}{{else}}{{range .Files}}{{.}}{{end}}{{end}}
 You can indicate your approval by writing `+"`/approve`"+` in a comment
 You can cancel your approval by writing `+"`/approve cancel`"+` in a comment
-</details>`, "message", map[string]interface{}{"ap": ap, "org": org, "project": project})
-
-	*message += getGubernatorMetadata(ap.GetCCs())
+</details>`
+
+// GetMessageWithTemplate is like GetMessage, but renders the body of the
+// notification with tmpl, executed with the ApprovalState of the PR. The
+// default template is used if tmpl is empty or fails to render. The
+// gubernator metadata is always appended.
+func GetMessageWithTemplate(ap Approvers, org, project, branch, tmpl string) *string {
+	return GetMessageWithOptions(ap, org, project, branch, MessageOptions{Template: tmpl})
+}
 
-	title := GenerateTemplateOrFail("This PR is **{{if not .IsApproved}}NOT {{end}}APPROVED**", "title", ap)
+// MessageOptions customize the notification rendered by
+// GetMessageWithOptions. The zero value renders the default message.
+type MessageOptions struct {
+	// Template renders the body of the notification, see
+	// GetMessageWithTemplate.
+	Template string
+	// OmitSuggestions leaves out the suggested approvers and reviewers,
+	// e.g. for repos where another bot assigns them.
+	OmitSuggestions bool
+	// GroupFilesByArea renders the OWNERS files under a heading per
+	// top-level directory, see GroupFilesByArea.
+	GroupFilesByArea bool
+	// NotificationName names the notification, so that several approval
+	// bots of a repo can each update their own. Defaults to
+	// ApprovalNotificationName.
+	NotificationName string
+}
 
-	if title == nil || message == nil {
+// GetMessageWithOptions is like GetMessage, customized with opts.
+func GetMessageWithOptions(ap Approvers, org, project, branch string, opts MessageOptions) *string {
+	message, err := GetMessageWithOptionsE(ap, org, project, branch, opts)
+	if err != nil && opts.Template != "" && opts.Template != DefaultMessageTemplate {
+		glog.Errorf("Falling back to the default approval message template: %v", err)
+		opts.Template = ""
+		message, err = GetMessageWithOptionsE(ap, org, project, branch, opts)
+	}
+	if err != nil {
+		glog.Error(err)
 		return nil
 	}
+	return &message
+}
+
+// GetMessageWithOptionsE is like GetMessageWithOptions, but returns the
+// error if the message fails to render. It doesn't fall back to the
+// default template.
+func GetMessageWithOptionsE(ap Approvers, org, project, branch string, opts MessageOptions) (string, error) {
+	state := GetApprovalState(ap, org, project, branch)
+	toBeAssigned := state.SuggestedCCs
+	if opts.OmitSuggestions {
+		state.OmitSuggestions = true
+		state.SuggestedCCs = nil
+		state.SuggestedReviewers = nil
+	}
+	if opts.GroupFilesByArea {
+		state.FileGroups = GroupFilesByArea(state.Files)
+	}
+	tmpl := opts.Template
+	if tmpl == "" {
+		tmpl = DefaultMessageTemplate
+	}
+	message, err := GenerateTemplate(tmpl, "message", state)
+	if err != nil {
+		return "", err
+	}
+	title, err := GenerateTemplate("This PR is **{{if not .Approved}}NOT {{end}}APPROVED**{{if .Holds}}, **ON HOLD**{{end}}", "title", state)
+	if err != nil {
+		return "", err
+	}
+	message += getGubernatorMetadata(ap, toBeAssigned)
+
+	name := opts.NotificationName
+	if name == "" {
+		name = ApprovalNotificationName
+	}
+	return (&c.Notification{Name: name, Arguments: title, Context: message}).String(), nil
+}
+
+// plainTextMessageTemplate renders the ApprovalState of a PR without
+// markdown, see GetPlainTextMessage.
+const plainTextMessageTemplate = `This PR is {{if not .Approved}}NOT {{end}}APPROVED{{if .Holds}}, ON HOLD{{end}}
+
+This pull-request has been approved by: {{range $index, $approval := .Approvals}}{{if $index}}, {{end}}{{$approval.Login}}{{end}}
+{{- if .SingleApprover}}
+Approved by {{.SingleApprover}} (covers all files)
+{{- end}}
+{{- if .Holds}}
+On hold by {{range $index, $hold := .Holds}}{{if $index}}, {{end}}{{$hold}}{{end}}, this PR must not merge until the hold{{if ne 1 (len .Holds)}}s are{{else}} is{{end}} removed.
+{{- end}}
+{{- if .MissingDistinctApprovers}}
+This PR needs the approval of {{.MissingDistinctApprovers}} more approver{{if ne 1 .MissingDistinctApprovers}}s{{end}} from the OWNERS files, to be approved by {{.MinDistinctApprovers}} different approvers.
+{{- end}}
+{{- if not .Approved}}
+{{- if .SuggestedCCs}}
+We suggest the following additional approver{{if ne 1 (len .SuggestedCCs)}}s{{end}}: {{range $index, $cc := .SuggestedCCs}}{{if $index}}, {{end}}{{$cc}}{{end}}
+{{- end}}
+{{- if .SuggestedReviewers}}
+We suggest the following reviewer{{if ne 1 (len .SuggestedReviewers)}}s{{end}}: {{range $index, $reviewer := .SuggestedReviewers}}{{if $index}}, {{end}}{{$reviewer}}{{end}}
+{{- end}}
+{{- end}}
+
+Needs approval from an approver in each of these OWNERS Files:
+{{range .FileLines}}{{.}}
+{{end}}
+You can indicate your approval by writing /approve in a comment
+You can cancel your approval by writing /approve cancel in a comment
+`
+
+// plainTextState is the ApprovalState with the files rendered without
+// markdown.
+type plainTextState struct {
+	ApprovalState
+	FileLines []string
+}
+
+// GetPlainTextMessage renders the same content as GetMessage without
+// markdown, for channels that don't render it, e.g. chat or email.
+// Links point to the default branch. nil is returned if it fails to
+// render.
+func GetPlainTextMessage(ap Approvers, org, project string) *string {
+	state := plainTextState{ApprovalState: GetApprovalState(ap, org, project, "")}
+	for _, file := range state.Files {
+		if plain, ok := file.(plainTextFile); ok {
+			state.FileLines = append(state.FileLines, plain.plainText())
+		} else {
+			state.FileLines = append(state.FileLines, strings.TrimSuffix(file.String(), "\n"))
+		}
+	}
+	return GenerateTemplateOrFail(plainTextMessageTemplate, "plain text message", state)
+}
+
+// ApprovalState is the approval status of a PR, as rendered by GetMessage.
+type ApprovalState struct {
+	Approved  bool
+	Approvals []Approval
+	// SuggestedCCs are the approvers we suggest to assign.
+	SuggestedCCs []string
+	// SuggestedReviewers is only set if SuggestReviewers is set.
+	SuggestedReviewers []string
+	Files              []File
+	// FileGroups are the Files grouped by top-level directory. It is
+	// only set if GroupFilesByArea is set, see MessageOptions.
+	FileGroups []FileGroup
+	// ApprovedFiles out of TotalFiles OWNERS files are approved, e.g.
+	// to render "{{.ApprovedFiles}} of {{.TotalFiles}} OWNERS files
+	// approved" in a custom template.
+	ApprovedFiles int
+	TotalFiles    int
+	// SingleApprover is only set if ShowSingleApprover is set and a
+	// single approver covers all the files.
+	SingleApprover string
+	// OmitSuggestions is set if the suggestions shouldn't be rendered,
+	// see MessageOptions.
+	OmitSuggestions bool
+	// Holds are the people who put the PR on hold, see IsBlocked.
+	Holds []string
+	// MissingDistinctApprovers is how many more approvers must approve
+	// the PR to reach MinDistinctApprovers, see
+	// Approvers.MinDistinctApprovers.
+	MinDistinctApprovers     int
+	MissingDistinctApprovers int
+}
+
+// GetApprovalState returns the approval status of the PR, so that callers
+// can render it or monitor it. Links point to the given branch, or to
+// the default branch if branch is empty, see GetFiles.
+func GetApprovalState(ap Approvers, org, project, branch string) ApprovalState {
+	state := ApprovalState{
+		Approved:     ap.IsApproved(),
+		Approvals:    ap.ListApprovals(),
+		SuggestedCCs: ap.GetCCs(),
+		Files:        ap.GetFiles(org, project, branch),
+		Holds:        ap.holds.List(),
+	}
+	if ap.SuggestReviewers {
+		state.SuggestedReviewers = ap.GetSuggestedReviewers()
+	}
+	state.ApprovedFiles, state.TotalFiles = ap.ApprovalProgress()
+	state.MinDistinctApprovers = ap.MinDistinctApprovers
+	state.MissingDistinctApprovers = ap.missingDistinctApprovers(ap.MinDistinctApprovers)
+	if ap.ShowSingleApprover {
+		state.SingleApprover, _ = ap.SingleApproverCovered()
+	}
+	return state
+}
+
+// ApprovalStateDiff compares the current approvers and the suggested
+// approvers of two evaluations of a PR. changed is false if both are the
+// same, so that callers can skip updating their comment.
+func ApprovalStateDiff(old, new Approvers) (addedApprovers, removedApprovers, addedCCs, removedCCs []string, changed bool) {
+	oldApprovers, newApprovers := old.GetCurrentApproversSet(), new.GetCurrentApproversSet()
+	oldCCs, newCCs := sets.NewString(old.GetCCs()...), sets.NewString(new.GetCCs()...)
+
+	addedApprovers = newApprovers.Difference(oldApprovers).List()
+	removedApprovers = oldApprovers.Difference(newApprovers).List()
+	addedCCs = newCCs.Difference(oldCCs).List()
+	removedCCs = oldCCs.Difference(newCCs).List()
+	changed = len(addedApprovers)+len(removedApprovers)+len(addedCCs)+len(removedCCs) != 0
+	return addedApprovers, removedApprovers, addedCCs, removedCCs, changed
+}
+
+// gubernatorMetadataVersion is bumped when the fields of the metadata
+// change. Version 2 added the version and the SUMMARY comment.
+const gubernatorMetadataVersion = 2
+
+// gubernatorMetadata is the machine-readable information about approvers
+// embedded in the notification.
+type gubernatorMetadata struct {
+	Approvers  []string            `json:"approvers"`
+	Files      map[string][]string `json:"files"`
+	Unapproved []string            `json:"unapproved"`
+	Version    int                 `json:"version"`
+}
 
-	notif := (&c.Notification{ApprovalNotificationName, *title, *message}).String()
-	return &notif
+// gubernatorSummary is a compact summary of the approval status embedded
+// in the notification, cheaper to parse than gubernatorMetadata.
+type gubernatorSummary struct {
+	Approved      bool `json:"approved"`
+	OnHold        bool `json:"on_hold,omitempty"`
+	ApprovedFiles int  `json:"approved_files"`
+	TotalFiles    int  `json:"total_files"`
+	Suggested     int  `json:"suggested"`
 }
 
 // getGubernatorMetadata returns a JSON string with machine-readable information about approvers.
 // This MUST be kept in sync with gubernator/github/classifier.py, particularly get_approvers.
-func getGubernatorMetadata(toBeAssigned []string) string {
-	bytes, err := json.Marshal(map[string][]string{"approvers": toBeAssigned})
-	if err == nil {
-		return fmt.Sprintf("\n<!-- META=%s -->", bytes)
+func getGubernatorMetadata(ap Approvers, toBeAssigned []string) string {
+	files := map[string][]string{}
+	for fn, approvers := range ap.GetFilesApprovers() {
+		files[fn] = approvers.List()
 	}
-	return ""
+	bytes, err := json.Marshal(gubernatorMetadata{
+		Approvers:  toBeAssigned,
+		Files:      files,
+		Unapproved: ap.UnapprovedFiles().List(),
+		Version:    gubernatorMetadataVersion,
+	})
+	if err != nil {
+		return ""
+	}
+	summary := gubernatorSummary{
+		Approved:  ap.IsApproved(),
+		OnHold:    ap.IsBlocked(),
+		Suggested: len(toBeAssigned),
+	}
+	summary.ApprovedFiles, summary.TotalFiles = ap.ApprovalProgress()
+	summaryBytes, err := json.Marshal(summary)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n<!-- META=%s -->\n<!-- SUMMARY=%s -->", bytes, summaryBytes)
 }
@@ -17,13 +17,17 @@ limitations under the License.
 package approvers
 
 import (
+	"fmt"
 	"testing"
 
 	"k8s.io/kubernetes/pkg/util/sets"
+	"k8s.io/test-infra/mungegithub/features"
 
+	"math/rand"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
 const (
@@ -33,8 +37,19 @@ const (
 )
 
 type FakeRepo struct {
-	ApproversMap     map[string]sets.String
-	LeafApproversMap map[string]sets.String
+	ApproversMap      map[string]sets.String
+	LeafApproversMap  map[string]sets.String
+	NoParentOwnersMap sets.String
+	EmeritusMap       map[string]sets.String
+	ReviewersMap      map[string]sets.String
+	LeafReviewersMap  map[string]sets.String
+	LabelsMap         map[string]sets.String
+	// RequiredLabelsMap makes the FakeRepo a RequiredLabeler.
+	RequiredLabelsMap map[string]sets.String
+	// RequiredApprovalsMap defaults to 1 for missing directories.
+	RequiredApprovalsMap map[string]int
+	RequiredApproversMap map[string]sets.String
+	BackupMap            map[string]sets.String
 }
 
 func (f FakeRepo) Org() string {
@@ -53,6 +68,49 @@ func (f FakeRepo) LeafApprovers(path string) sets.String {
 	return f.LeafApproversMap[path]
 }
 
+func (f FakeRepo) Reviewers(path string) sets.String {
+	return f.ReviewersMap[path]
+}
+
+func (f FakeRepo) LeafReviewers(path string) sets.String {
+	return f.LeafReviewersMap[path]
+}
+
+func (f FakeRepo) EmeritusApprovers(path string) sets.String {
+	return f.EmeritusMap[path]
+}
+
+func (f FakeRepo) Labels(path string) sets.String {
+	return f.LabelsMap[path]
+}
+
+func (f FakeRepo) RequiredLabels(path string) sets.String {
+	return f.RequiredLabelsMap[path]
+}
+
+func (f FakeRepo) RequiredApprovals(path string) int {
+	if required, ok := f.RequiredApprovalsMap[path]; ok {
+		return required
+	}
+	return 1
+}
+
+func (f FakeRepo) RequiredApprovers(path string) sets.String {
+	return f.RequiredApproversMap[path]
+}
+
+func (f FakeRepo) PrimaryApprovers(path string) sets.String {
+	return f.ApproversMap[path].Difference(f.BackupMap[path])
+}
+
+func (f FakeRepo) BackupApprovers(path string) sets.String {
+	return f.BackupMap[path]
+}
+
+func (f FakeRepo) IsNoParentOwners(path string) bool {
+	return f.NoParentOwnersMap.Has(path)
+}
+
 func (f FakeRepo) FindApproverOwnersForPath(path string) string {
 	dir, _ := filepath.Split(path)
 	for dir != "." {
@@ -77,12 +135,21 @@ func canonicalize(path string) string {
 }
 
 func createFakeRepo(la map[string]sets.String) FakeRepo {
+	return createFakeRepoNoParentOwners(la, sets.NewString())
+}
+
+// createFakeRepoNoParentOwners creates a fake repo where the directories in
+// noParentOwners don't inherit approvers from their parents.
+func createFakeRepoNoParentOwners(la map[string]sets.String, noParentOwners sets.String) FakeRepo {
 	// github doesn't use / at the root
 	a := map[string]sets.String{}
 	for dir, approvers := range la {
 		a[dir] = approvers
 		starting_path := dir
 		for {
+			if noParentOwners.Has(dir) {
+				break
+			}
 			dir = canonicalize(filepath.Dir(dir))
 			if parent_approvers, ok := la[dir]; ok {
 				a[starting_path] = a[starting_path].Union(parent_approvers)
@@ -93,7 +160,7 @@ func createFakeRepo(la map[string]sets.String) FakeRepo {
 		}
 	}
 
-	return FakeRepo{ApproversMap: a, LeafApproversMap: la}
+	return FakeRepo{ApproversMap: a, LeafApproversMap: la, NoParentOwnersMap: noParentOwners}
 }
 func TestCreateFakeRepo(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
@@ -219,6 +286,44 @@ func TestGetLeafApprovers(t *testing.T) {
 		}
 	}
 }
+func TestOwnersForFiles(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":    sets.NewString("Alice", "Bob"),
+		"a":   sets.NewString("Art", "Anne"),
+		"b":   sets.NewString("Bill", "Ben"),
+		"a/d": sets.NewString("David", "Dan"),
+	}
+
+	tests := []struct {
+		testName       string
+		filenames      []string
+		expectedOwners map[string]string
+	}{
+		{
+			testName:       "Empty PR",
+			filenames:      []string{},
+			expectedOwners: map[string]string{},
+		},
+		{
+			testName:       "Same OWNERS file",
+			filenames:      []string{"a/test.go", "a/c/test.go"},
+			expectedOwners: map[string]string{"a/test.go": "a", "a/c/test.go": "a"},
+		},
+		{
+			testName:       "Different OWNERS files",
+			filenames:      []string{"kubernetes.go", "a/test.go", "a/d/test.go", "b/test.go"},
+			expectedOwners: map[string]string{"kubernetes.go": "", "a/test.go": "a", "a/d/test.go": "a/d", "b/test.go": "b"},
+		},
+	}
+
+	for _, test := range tests {
+		testOwners := Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+		if calculated := testOwners.OwnersForFiles(); !reflect.DeepEqual(test.expectedOwners, calculated) {
+			t.Errorf("Failed for test %v.  Expected owners: %v. Found %v", test.testName, test.expectedOwners, calculated)
+		}
+	}
+}
+
 func TestGetOwnersSet(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
 	aApprovers := sets.NewString("Art", "Anne")
@@ -277,6 +382,66 @@ func TestGetOwnersSet(t *testing.T) {
 	}
 }
 
+func TestNoParentOwners(t *testing.T) {
+	rootApprovers := sets.NewString("Alice", "Bob")
+	aApprovers := sets.NewString("Art", "Anne")
+	secApprovers := sets.NewString("Sam", "Sue")
+	FakeRepoMap := map[string]sets.String{
+		"":      rootApprovers,
+		"a":     aApprovers,
+		"a/sec": secApprovers,
+	}
+	fakeRepo := createFakeRepoNoParentOwners(FakeRepoMap, sets.NewString("a/sec"))
+
+	if approvers := fakeRepo.Approvers("a/sec"); !approvers.Equal(secApprovers) {
+		t.Errorf("Expected approvers of a/sec to be %v, found %v", secApprovers, approvers)
+	}
+
+	tests := []struct {
+		testName            string
+		filenames           []string
+		currentlyApproved   sets.String
+		expectedOwnersFiles sets.String
+		expectedUnapproved  sets.String
+	}{
+		{
+			testName:            "Root approver can't approve the leaf",
+			filenames:           []string{"a/sec/test.go"},
+			currentlyApproved:   rootApprovers,
+			expectedOwnersFiles: sets.NewString("a/sec"),
+			expectedUnapproved:  sets.NewString("a/sec"),
+		},
+		{
+			testName:            "Leaf is kept next to the root",
+			filenames:           []string{"kubernetes.go", "a/test.go", "a/sec/test.go"},
+			currentlyApproved:   rootApprovers,
+			expectedOwnersFiles: sets.NewString("", "a/sec"),
+			expectedUnapproved:  sets.NewString("a/sec"),
+		},
+		{
+			testName:            "Leaf approver approves the leaf",
+			filenames:           []string{"kubernetes.go", "a/sec/test.go"},
+			currentlyApproved:   sets.NewString("Alice", "Sam"),
+			expectedOwnersFiles: sets.NewString("", "a/sec"),
+			expectedUnapproved:  sets.NewString(),
+		},
+	}
+
+	for _, test := range tests {
+		testOwners := Owners{filenames: test.filenames, repo: fakeRepo, seed: TEST_SEED}
+		if oSet := testOwners.GetOwnersSet(); !oSet.Equal(test.expectedOwnersFiles) {
+			t.Errorf("Failed for test %v.  Expected Owners: %v. Actual Owners %v", test.testName, test.expectedOwnersFiles, oSet)
+		}
+		testApprovers := NewApprovers(testOwners)
+		for approver := range test.currentlyApproved {
+			testApprovers.AddApprover(approver, "REFERENCE", "")
+		}
+		if unapproved := testApprovers.UnapprovedFiles(); !unapproved.Equal(test.expectedUnapproved) {
+			t.Errorf("Failed for test %v.  Expected unapproved files: %v. Found %v", test.testName, test.expectedUnapproved, unapproved)
+		}
+	}
+}
+
 func TestGetSuggestedApprovers(t *testing.T) {
 	var rootApprovers = sets.NewString("Alice", "Bob")
 	var aApprovers = sets.NewString("Art", "Anne")
@@ -351,6 +516,257 @@ func TestGetSuggestedApprovers(t *testing.T) {
 	}
 }
 
+func TestGetSuggestedApproversUsesUnapprovedCoverage(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Paul", "Xena"),
+		"b": sets.NewString("Paul", "Xena"),
+		"c": sets.NewString("Paul", "Xena"),
+		"d": sets.NewString("Paul"),
+		"e": sets.NewString("Xena", "Yuri"),
+		"f": sets.NewString("Yuri", "Zoe"),
+	}
+	testOwners := Owners{
+		filenames: []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go", "e/test.go", "f/test.go"},
+		repo:      createFakeRepo(FakeRepoMap),
+		seed:      TEST_SEED,
+	}
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+
+	// Once Paul is picked, only "e" and "f" are left. Xena can approve
+	// more files overall, but Yuri is the one covering both of them.
+	unapproved := sets.NewString("e", "f")
+	if best := findMostCoveringApprover([]string{"Paul", "Xena", "Yuri", "Zoe"}, reverseMap, unapproved); best != "Yuri" {
+		t.Errorf("Expected most covering approver to be Yuri, found %v", best)
+	}
+
+	expected := sets.NewString("Paul", "Yuri")
+	suggested := testOwners.GetSuggestedApprovers(reverseMap, []string{"Paul", "Xena", "Yuri", "Zoe"})
+	if !suggested.Equal(expected) {
+		t.Errorf("Expected suggested approvers %v, found %v", expected, suggested)
+	}
+}
+
+func TestGetSuggestedApproversE(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	testOwners := Owners{
+		filenames: []string{"a/test.go", "b/test.go"},
+		repo:      createFakeRepoNoParentOwners(FakeRepoMap, sets.NewString("a", "b")),
+		seed:      TEST_SEED,
+		excluded:  sets.NewString("Bill"),
+	}
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+
+	suggested, uncoverable := testOwners.GetSuggestedApproversE(reverseMap, testOwners.GetShuffledApprovers())
+	if expected := sets.NewString("Art"); !suggested.Equal(expected) {
+		t.Errorf("Expected suggested approvers %v, found %v", expected, suggested)
+	}
+	if expected := sets.NewString("b"); !uncoverable.Equal(expected) {
+		t.Errorf("Expected uncoverable files %v, found %v", expected, uncoverable)
+	}
+
+	testOwners.excluded = nil
+	if _, uncoverable := testOwners.GetSuggestedApproversE(reverseMap, testOwners.GetShuffledApprovers()); uncoverable.Len() != 0 {
+		t.Errorf("Expected no uncoverable files, found %v", uncoverable)
+	}
+}
+
+func TestGetMinimalSpreadApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a/x": sets.NewString("Pat", "Ben"),
+		"a/y": sets.NewString("Pat", "Bill"),
+		"b/z": sets.NewString("Ben", "Bill", "Zed"),
+		"b/w": sets.NewString("Ben", "Bill", "Zed"),
+	}
+	filenames := []string{"a/x/test.go", "a/y/test.go", "b/z/test.go", "b/w/test.go"}
+
+	testOwners := Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+	if spread := teamSpread([]string{"Ben", "Bill"}, reverseMap); spread != 1 {
+		t.Errorf("Expected a spread of 1 for Ben and Bill. Found %v", spread)
+	}
+	if spread := teamSpread([]string{"Pat", "Zed"}, reverseMap); spread != 2 {
+		t.Errorf("Expected a spread of 2 for Pat and Zed. Found %v", spread)
+	}
+
+	for seed := int64(0); seed < 10; seed++ {
+		testOwners := Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: seed}
+		reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+		potentialApprovers := testOwners.GetShuffledApprovers()
+
+		// {Pat, Zed} is as small, but spreads over two teams.
+		if calculated, expected := testOwners.GetMinimalSpreadApprovers(reverseMap, potentialApprovers), sets.NewString("Ben", "Bill"); !expected.Equal(calculated) {
+			t.Errorf("Failed for seed %v.  Expected approvers: %v. Found %v", seed, expected, calculated)
+		}
+		if calculated := testOwners.GetMinimalApprovers(reverseMap, potentialApprovers); calculated.Len() != 2 {
+			t.Errorf("Failed for seed %v.  Expected 2 approvers. Found %v", seed, calculated)
+		}
+
+		testApprovers := NewApprovers(testOwners)
+		testApprovers.MinimizeSpread = true
+		if calculated, expected := testApprovers.GetCCs(), []string{"Ben", "Bill"}; !reflect.DeepEqual(expected, calculated) {
+			t.Errorf("Failed for seed %v.  Expected CCs: %v. Found %v", seed, expected, calculated)
+		}
+	}
+}
+
+func TestGetMinimalApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Xena", "Anne"),
+		"b": sets.NewString("Xena", "Anne"),
+		"c": sets.NewString("Xena", "Bill"),
+		"d": sets.NewString("Xena", "Bill"),
+		"e": sets.NewString("Anne"),
+		"f": sets.NewString("Bill"),
+	}
+	filenames := []string{"a/test.go", "b/test.go", "c/test.go", "d/test.go", "e/test.go", "f/test.go"}
+	potentialApprovers := []string{"Xena", "Anne", "Bill"}
+
+	tests := []struct {
+		testName          string
+		filenames         []string
+		threshold         int
+		expectedApprovers sets.String
+	}{
+		{
+			testName:          "Empty PR",
+			filenames:         []string{},
+			threshold:         15,
+			expectedApprovers: sets.NewString(),
+		},
+		{
+			testName:          "Exact Search",
+			filenames:         filenames,
+			threshold:         15,
+			expectedApprovers: sets.NewString("Anne", "Bill"),
+		},
+		{
+			testName:          "Greedy Above Threshold",
+			filenames:         filenames,
+			threshold:         5,
+			expectedApprovers: sets.NewString("Xena", "Anne", "Bill"),
+		},
+	}
+
+	defer func(threshold int) { MinimalApproversThreshold = threshold }(MinimalApproversThreshold)
+	for _, test := range tests {
+		MinimalApproversThreshold = test.threshold
+		testOwners := Owners{filenames: test.filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+		reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+		calculated := testOwners.GetMinimalApprovers(reverseMap, potentialApprovers)
+		if !calculated.Equal(test.expectedApprovers) {
+			t.Errorf("Failed for test %v.  Expected approvers: %v. Found %v", test.testName, test.expectedApprovers, calculated)
+		}
+	}
+
+	// Past the node budget, the greedy cover is kept.
+	defer func(max int) { MaxCoverSearchNodes = max }(MaxCoverSearchNodes)
+	MaxCoverSearchNodes = 0
+	testOwners := Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+	if calculated, expected := testOwners.GetMinimalApprovers(reverseMap, potentialApprovers), sets.NewString("Xena", "Anne", "Bill"); !calculated.Equal(expected) {
+		t.Errorf("Expected the greedy approvers %v past the node budget. Found %v", expected, calculated)
+	}
+}
+
+// disjointOwners returns the Owners of a PR touching n OWNERS files with
+// 5 approvers each, none of them in several files.
+func disjointOwners(n int) Owners {
+	FakeRepoMap := map[string]sets.String{}
+	filenames := []string{}
+	for i := 0; i < n; i++ {
+		dir := fmt.Sprintf("dir%d", i)
+		approvers := sets.NewString()
+		for j := 0; j < 5; j++ {
+			approvers.Insert(fmt.Sprintf("Approver%d-%d", i, j))
+		}
+		FakeRepoMap[dir] = approvers
+		filenames = append(filenames, dir+"/test.go")
+	}
+	return Owners{filenames: filenames, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+}
+
+func TestGetMinimalApproversDisjointFiles(t *testing.T) {
+	defer func(max int) { MaxCoverSearchNodes = max }(MaxCoverSearchNodes)
+	// The search must be pruned rather than cut short.
+	MaxCoverSearchNodes = 1 << 30
+
+	testOwners := disjointOwners(MinimalApproversThreshold)
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+	potentialApprovers := testOwners.GetAllPotentialApprovers()
+	start := time.Now()
+	minimal := testOwners.GetMinimalApprovers(reverseMap, potentialApprovers)
+	spread := testOwners.GetMinimalSpreadApprovers(reverseMap, potentialApprovers)
+	assign := NewApprovers(testOwners).MinimalAssignRequest()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the minimal approvers to be found quickly. Took %v", elapsed)
+	}
+	for name, found := range map[string]int{"minimal": minimal.Len(), "minimal spread": spread.Len(), "assign request": len(assign)} {
+		if found != MinimalApproversThreshold {
+			t.Errorf("Expected %v %v approvers. Found %v", MinimalApproversThreshold, name, found)
+		}
+	}
+}
+
+func BenchmarkGetMinimalApprovers(b *testing.B) {
+	testOwners := disjointOwners(MinimalApproversThreshold)
+	reverseMap := testOwners.GetReverseMap(testOwners.GetLeafApprovers())
+	potentialApprovers := testOwners.GetAllPotentialApprovers()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testOwners.GetMinimalApprovers(reverseMap, potentialApprovers)
+	}
+}
+
+func TestGetSuggestedReviewers(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Anne"),
+		"b": sets.NewString("Bill"),
+	})
+	fakeRepo.LeafReviewersMap = map[string]sets.String{
+		"a": sets.NewString("Rachel", "Roger"),
+		"b": sets.NewString("Rachel", "Ralph"),
+	}
+	tests := []struct {
+		testName          string
+		filenames         []string
+		expectedReviewers sets.String
+	}{
+		{
+			testName:          "Empty PR",
+			filenames:         []string{},
+			expectedReviewers: sets.NewString(),
+		},
+		{
+			testName:          "Single File PR",
+			filenames:         []string{"b/test.go"},
+			expectedReviewers: nil, // either Rachel or Ralph
+		},
+		{
+			testName:          "One Reviewer Covers Both",
+			filenames:         []string{"a/test.go", "b/test.go"},
+			expectedReviewers: sets.NewString("Rachel"),
+		},
+	}
+
+	for _, test := range tests {
+		testOwners := Owners{filenames: test.filenames, repo: fakeRepo, seed: TEST_SEED}
+		suggested := testOwners.GetSuggestedReviewers()
+		if test.expectedReviewers == nil {
+			if suggested.Len() != 1 || !fakeRepo.LeafReviewersMap["b"].IsSuperset(suggested) {
+				t.Errorf("Failed for test %v.  Expected one reviewer from %v. Found %v", test.testName, fakeRepo.LeafReviewersMap["b"], suggested)
+			}
+		} else if !suggested.Equal(test.expectedReviewers) {
+			t.Errorf("Failed for test %v.  Expected reviewers: %v. Found %v", test.testName, test.expectedReviewers, suggested)
+		}
+		if approvers := testOwners.GetSuggestedApprovers(testOwners.GetReverseMap(testOwners.GetLeafApprovers()), testOwners.GetShuffledApprovers()); approvers.HasAny(suggested.List()...) {
+			t.Errorf("Failed for test %v.  Expected reviewers %v to differ from approvers %v", test.testName, suggested, approvers)
+		}
+	}
+}
+
 func TestGetAllPotentialApprovers(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
 	aApprovers := sets.NewString("Art", "Anne")
@@ -418,6 +834,11 @@ func TestGetAllPotentialApprovers(t *testing.T) {
 			filenames:         []string{"a/combo/test.go", "b/test.go", "c/test.go", "d/test.go"},
 			expectedApprovers: rootApprovers.List(),
 		},
+		{
+			testName:          "Approvers In Two Leafs Listed Once",
+			filenames:         []string{"a/combo/test.go", "a/d/test.go"},
+			expectedApprovers: edcApprovers.List(),
+		},
 	}
 
 	for _, test := range tests {
@@ -426,6 +847,350 @@ func TestGetAllPotentialApprovers(t *testing.T) {
 		if !reflect.DeepEqual(all, test.expectedApprovers) {
 			t.Errorf("Failed for test %v.  Didn't correct approvers list.  Expected: %v. Found %v", test.testName, test.expectedApprovers, all)
 		}
+		if count := testOwners.PotentialApproverCount(); count != len(test.expectedApprovers) {
+			t.Errorf("Failed for test %v.  Expected %v potential approvers. Found %v", test.testName, len(test.expectedApprovers), count)
+		}
+	}
+}
+
+func TestRepoAliasValidate(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("team/valid", "team/unknown", "alice"),
+	})
+	fakeRepo.ReviewersMap = map[string]sets.String{
+		"a": sets.NewString("bob", "team/typo"),
+	}
+	repo := NewRepoAlias(fakeRepo, *features.NewAliases(map[string][]string{
+		"team/valid": {"carol", "dave"},
+	}))
+
+	if calculated, expected := repo.Validate("a"), []string{"team/typo", "team/unknown"}; !reflect.DeepEqual(calculated, expected) {
+		t.Errorf("Expected unresolved names %v. Found %v", expected, calculated)
+	}
+	if calculated := repo.Validate("b"); len(calculated) != 0 {
+		t.Errorf("Expected no unresolved names for a missing OWNERS. Found %v", calculated)
+	}
+}
+
+// parseErrorRepo is a FakeRepo whose OWNERS files in errs failed to parse.
+type parseErrorRepo struct {
+	FakeRepo
+	errs map[string]error
+}
+
+func (r parseErrorRepo) ParseErrors(path string) map[string]error {
+	errs := map[string]error{}
+	for dir, err := range r.errs {
+		if dir == "" || path == dir || strings.HasPrefix(path, dir+"/") {
+			errs[dir] = err
+		}
+	}
+	return errs
+}
+
+func TestOwnersValidate(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("alice"),
+		"b": sets.NewString(),
+		"c": sets.NewString("team/typo"),
+	})
+	tests := []struct {
+		testName         string
+		filenames        []string
+		repo             RepoInterface
+		expectedProblems []string
+	}{
+		{
+			testName:         "Clean",
+			filenames:        []string{"a/a.go"},
+			repo:             NewRepoAlias(fakeRepo, *features.NewAliases(map[string][]string{})),
+			expectedProblems: []string{},
+		},
+		{
+			testName:         "Empty Approvers",
+			filenames:        []string{"a/a.go", "b/b.go"},
+			repo:             fakeRepo,
+			expectedProblems: []string{"b/OWNERS: empty approvers"},
+		},
+		{
+			testName:         "Custom File Name",
+			filenames:        []string{"a/a.go", "b/b.go"},
+			repo:             namingRepo{fakeRepo, map[string]string{"b": "MAINTAINERS"}},
+			expectedProblems: []string{"b/MAINTAINERS: empty approvers"},
+		},
+		{
+			testName:         "Unresolved Alias",
+			filenames:        []string{"c/c.go"},
+			repo:             NewRepoAlias(fakeRepo, *features.NewAliases(map[string][]string{})),
+			expectedProblems: []string{`c/OWNERS: unresolved alias "team/typo"`},
+		},
+		{
+			testName:  "Parse Error",
+			filenames: []string{"a/a.go", "d/d.go"},
+			repo: NewRepoAlias(parseErrorRepo{fakeRepo, map[string]error{
+				"d": fmt.Errorf("invalid yaml"),
+				"e": fmt.Errorf("invalid yaml"),
+			}}, *features.NewAliases(map[string][]string{})),
+			expectedProblems: []string{"d/OWNERS: parse error: invalid yaml", "OWNERS: empty approvers"},
+		},
+	}
+
+	for _, test := range tests {
+		problems := []string{}
+		for _, problem := range NewOwners(test.filenames, test.repo, TEST_SEED).Validate() {
+			problems = append(problems, problem.String())
+		}
+		if !reflect.DeepEqual(problems, test.expectedProblems) {
+			t.Errorf("Failed for test %v.  Expected problems: %v. Found %v", test.testName, test.expectedProblems, problems)
+		}
+	}
+}
+
+func TestRepoAliasOverlappingAliases(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a":   sets.NewString("team/a", "team/b"),
+		"a/b": sets.NewString("team/b", "bob"),
+	})
+	repo := NewRepoAlias(fakeRepo, *features.NewAliases(map[string][]string{
+		"team/a": {"Alice", "bob"},
+		"team/b": {"alice", "bob", "carol"},
+	}))
+
+	if calculated, expected := repo.LeafApprovers("a"), sets.NewString("Alice", "bob", "carol"); !expected.Equal(calculated) {
+		t.Errorf("Expected leaf approvers %v. Found %v", expected, calculated)
+	}
+	if calculated, expected := repo.Approvers("a/b"), sets.NewString("Alice", "bob", "carol"); !expected.Equal(calculated) {
+		t.Errorf("Expected approvers %v. Found %v", expected, calculated)
+	}
+
+	owners := NewOwners([]string{"a/test.go", "a/b/test.go"}, repo, TEST_SEED)
+	if calculated := owners.PotentialApproverCount(); calculated != 3 {
+		t.Errorf("Expected 3 potential approvers. Found %v", calculated)
+	}
+	ap := NewApprovers(owners)
+	ap.AddApprover("ALICE", "REFERENCE", "")
+	if !ap.IsApproved() {
+		t.Errorf("Expected Alice to approve regardless of case")
+	}
+	if calculated, expected := ap.GetFilesApprovers()["a"], sets.NewString("ALICE"); !expected.Equal(calculated) {
+		t.Errorf("Expected file approvers %v. Found %v", expected, calculated)
+	}
+
+	repo.CaseSensitive = true
+	if calculated, expected := repo.LeafApprovers("a"), sets.NewString("Alice", "alice", "bob", "carol"); !expected.Equal(calculated) {
+		t.Errorf("Expected case-sensitive leaf approvers %v. Found %v", expected, calculated)
+	}
+}
+
+type fakeTeamExpander struct {
+	members map[string]sets.String
+	calls   int
+}
+
+func (f *fakeTeamExpander) ExpandTeam(team string) sets.String {
+	f.calls++
+	return f.members[team]
+}
+
+func TestRepoAliasTeams(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("@org/team", "alice"),
+		"b": sets.NewString("@org/team"),
+	})
+	teams := &fakeTeamExpander{members: map[string]sets.String{"@org/team": sets.NewString("carol", "dave")}}
+	repo := NewRepoAliasWithTeams(fakeRepo, *features.NewAliases(map[string][]string{}), teams)
+
+	if calculated, expected := repo.Approvers("a"), sets.NewString("alice", "carol", "dave"); !expected.Equal(calculated) {
+		t.Errorf("Expected approvers %v. Found %v", expected, calculated)
+	}
+	if calculated, expected := repo.LeafApprovers("b"), sets.NewString("carol", "dave"); !expected.Equal(calculated) {
+		t.Errorf("Expected leaf approvers %v. Found %v", expected, calculated)
+	}
+	if teams.calls != 1 {
+		t.Errorf("Expected the team to be expanded once. Found %v expansions", teams.calls)
+	}
+	if calculated := repo.Validate("a"); len(calculated) != 0 {
+		t.Errorf("Expected team handles to be valid. Found unresolved names %v", calculated)
+	}
+
+	ap := NewApprovers(NewOwners([]string{"b/test.go"}, repo, TEST_SEED))
+	ap.AddApprover("dave", "REFERENCE", "")
+	if !ap.IsApproved() {
+		t.Errorf("Expected a member of the team to approve")
+	}
+}
+
+// fakeBatchTeamExpander counts how many times each team is expanded.
+type fakeBatchTeamExpander struct {
+	members  map[string]sets.String
+	expanded map[string]int
+	batches  int
+}
+
+func (f *fakeBatchTeamExpander) ExpandTeam(team string) sets.String {
+	return f.ExpandTeams([]string{team})[team]
+}
+
+func (f *fakeBatchTeamExpander) ExpandTeams(teams []string) map[string]sets.String {
+	f.batches++
+	result := map[string]sets.String{}
+	for _, team := range teams {
+		f.expanded[team]++
+		result[team] = f.members[team]
+	}
+	return result
+}
+
+func TestRepoAliasBatchTeams(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("@org/one", "@org/two"),
+		"b": sets.NewString("@org/one"),
+		"c": sets.NewString("@org/two", "alice"),
+	})
+	teams := &fakeBatchTeamExpander{
+		members: map[string]sets.String{
+			"@org/one": sets.NewString("bob"),
+			"@org/two": sets.NewString("carol"),
+		},
+		expanded: map[string]int{},
+	}
+	repo := NewRepoAliasWithTeams(fakeRepo, *features.NewAliases(map[string][]string{}), teams)
+	owners := NewOwners([]string{"a/test.go", "b/test.go", "c/test.go"}, repo, TEST_SEED)
+
+	expected := map[string]sets.String{
+		"a": sets.NewString("bob", "carol"),
+		"b": sets.NewString("bob"),
+		"c": sets.NewString("alice", "carol"),
+	}
+	if calculated := owners.GetApprovers(); !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected approvers %v. Found %v", expected, calculated)
+	}
+	owners.GetLeafApprovers()
+	if expected := map[string]int{"@org/one": 1, "@org/two": 1}; !reflect.DeepEqual(expected, teams.expanded) {
+		t.Errorf("Expected each team to be expanded once. Found %v", teams.expanded)
+	}
+	if teams.batches != 1 {
+		t.Errorf("Expected the teams to be expanded in a single batch. Found %v batches", teams.batches)
+	}
+}
+
+func TestGetRequiredLabels(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Anne"),
+		"b": sets.NewString("Bill"),
+		"c": sets.NewString("Carl"),
+	}
+	fakeRepo := createFakeRepo(FakeRepoMap)
+	fakeRepo.LabelsMap = map[string]sets.String{
+		"a": sets.NewString("sig/foo"),
+		"b": sets.NewString("sig/bar", "area/baz"),
+		"c": sets.NewString("sig/qux"),
+	}
+
+	testOwners := Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: fakeRepo, seed: TEST_SEED}
+	if calculated, expected := testOwners.GetRequiredLabels(), sets.NewString("sig/foo", "sig/bar", "area/baz"); !expected.Equal(calculated) {
+		t.Errorf("Expected labels %v. Found %v", expected, calculated)
+	}
+}
+
+func TestGetAllPotentialApproversDeduplicated(t *testing.T) {
+	fakeRepo := createFakeRepo(map[string]sets.String{
+		"a": sets.NewString("Sam", "Art"),
+		"b": sets.NewString("Sam", "Bill"),
+		"c": sets.NewString("team/shared"),
+	})
+	repo := NewRepoAlias(fakeRepo, *features.NewAliases(map[string][]string{
+		"team/shared": {"Sam", "Carl"},
+	}))
+	testOwners := Owners{filenames: []string{"a/test.go", "b/test.go", "c/test.go"}, repo: repo, seed: TEST_SEED}
+
+	expected := []string{"Art", "Bill", "Carl", "Sam"}
+	if calculated := testOwners.GetAllPotentialApprovers(); !reflect.DeepEqual(expected, calculated) {
+		t.Errorf("Expected potential approvers %v. Found %v", expected, calculated)
+	}
+	if calculated := testOwners.GetShuffledApprovers(); len(calculated) != len(expected) {
+		t.Errorf("Expected each approver to be shuffled once. Found %v", calculated)
+	}
+}
+
+func TestApproverChain(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":      sets.NewString("Alice"),
+		"a":     sets.NewString("Art"),
+		"a/b":   sets.NewString("Bob"),
+		"a/b/c": sets.NewString(),
+	}
+	tests := []struct {
+		testName       string
+		noParentOwners sets.String
+		path           string
+		expected       []OwnersLevel
+	}{
+		{
+			testName: "Two Levels",
+			path:     "a/test.go",
+			expected: []OwnersLevel{
+				{Dir: "a", Approvers: sets.NewString("Art")},
+				{Dir: "", Approvers: sets.NewString("Alice")},
+			},
+		},
+		{
+			testName: "Empty OWNERS Skipped",
+			path:     "a/b/c/test.go",
+			expected: []OwnersLevel{
+				{Dir: "a/b", Approvers: sets.NewString("Bob")},
+				{Dir: "a", Approvers: sets.NewString("Art")},
+				{Dir: "", Approvers: sets.NewString("Alice")},
+			},
+		},
+		{
+			testName:       "Truncated by no_parent_owners",
+			noParentOwners: sets.NewString("a"),
+			path:           "a/b/test.go",
+			expected: []OwnersLevel{
+				{Dir: "a/b", Approvers: sets.NewString("Bob")},
+				{Dir: "a", Approvers: sets.NewString("Art")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		repo := createFakeRepoNoParentOwners(FakeRepoMap, test.noParentOwners)
+		testOwners := Owners{filenames: []string{test.path}, repo: repo, seed: TEST_SEED}
+		if calculated := testOwners.ApproverChain(test.path); !reflect.DeepEqual(test.expected, calculated) {
+			t.Errorf("Failed for test %v.  Expected chain: %v. Found %v", test.testName, test.expected, calculated)
+		}
+	}
+}
+
+func TestEmeritusApprovers(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"e": sets.NewString("Eve", "Erin"),
+	}
+	fakeRepo := createFakeRepo(FakeRepoMap)
+	testOwners := Owners{filenames: []string{"e/test.go"}, repo: fakeRepo, seed: TEST_SEED}
+
+	if potential := testOwners.GetAllPotentialApprovers(); !reflect.DeepEqual(potential, []string{"Erin", "Eve"}) {
+		t.Errorf("Expected Erin and Eve to be potential approvers, found %v", potential)
+	}
+
+	fakeRepo.EmeritusMap = map[string]sets.String{"e": sets.NewString("Erin")}
+	testOwners = Owners{filenames: []string{"e/test.go"}, repo: fakeRepo, seed: TEST_SEED}
+	if potential := testOwners.GetAllPotentialApprovers(); !reflect.DeepEqual(potential, []string{"Eve"}) {
+		t.Errorf("Expected only Eve to be a potential approver, found %v", potential)
+	}
+	for seed := int64(0); seed < 10; seed++ {
+		testApprovers := NewApprovers(Owners{filenames: []string{"e/test.go"}, repo: fakeRepo, seed: seed})
+		if ccs := testApprovers.GetCCs(); !reflect.DeepEqual(ccs, []string{"Eve"}) {
+			t.Errorf("Expected Eve to be suggested with seed %v, found %v", seed, ccs)
+		}
+	}
+
+	// Emeritus approvers can still approve explicitly.
+	testApprovers := NewApprovers(testOwners)
+	testApprovers.AddApprover("Erin", "REFERENCE", "")
+	if !testApprovers.IsApproved() {
+		t.Errorf("Expected the emeritus approver to approve the PR")
 	}
 }
 
@@ -570,6 +1335,83 @@ func TestGetReverseMap(t *testing.T) {
 	}
 }
 
+func TestNewOwnersValidated(t *testing.T) {
+	tests := []struct {
+		testName          string
+		filenames         []string
+		expectedFilenames []string
+		expectErr         bool
+	}{
+		{
+			testName:          "Clean Filenames",
+			filenames:         []string{"a/test.go", "b/test.go"},
+			expectedFilenames: []string{"a/test.go", "b/test.go"},
+		},
+		{
+			testName:          "Dot Dot Segment",
+			filenames:         []string{"a/../b/test.go", "./a/test.go"},
+			expectedFilenames: []string{"b/test.go", "a/test.go"},
+		},
+		{
+			testName:          "Duplicates",
+			filenames:         []string{"a/test.go", "b/test.go", "a//test.go", "b/../a/test.go"},
+			expectedFilenames: []string{"a/test.go", "b/test.go"},
+		},
+		{
+			testName:  "Empty Filename",
+			filenames: []string{"a/test.go", ""},
+			expectErr: true,
+		},
+		{
+			testName:  "Outside Of Repository",
+			filenames: []string{"a/../../test.go"},
+			expectErr: true,
+		},
+		{
+			testName:  "Absolute Filename",
+			filenames: []string{"/etc/passwd"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		owners, err := NewOwnersValidated(test.filenames, createFakeRepo(map[string]sets.String{}), TEST_SEED)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Failed for test %v.  Expected an error", test.testName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed for test %v.  Unexpected error: %v", test.testName, err)
+			continue
+		}
+		if !reflect.DeepEqual(owners.filenames, test.expectedFilenames) {
+			t.Errorf("Failed for test %v.  Expected filenames: %v. Found %v", test.testName, test.expectedFilenames, owners.filenames)
+		}
+	}
+}
+
+func TestSeedForPR(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"a": sets.NewString("Anne", "Art", "Alex", "Amy", "Andy"),
+	}
+	shuffled := func(org, project string, number int) []string {
+		return NewOwners([]string{"a/test.go"}, createFakeRepo(FakeRepoMap), SeedForPR(org, project, number)).GetShuffledApprovers()
+	}
+
+	first := shuffled("org", "project", 42)
+	if second := shuffled("org", "project", 42); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same order for the same PR. Found %v and %v", first, second)
+	}
+	if SeedForPR("org", "project", 42) == SeedForPR("org", "project", 43) {
+		t.Errorf("Expected different seeds for different PRs")
+	}
+	if SeedForPR("org", "project", 42) == SeedForPR("org", "other", 42) {
+		t.Errorf("Expected different seeds for different projects")
+	}
+}
+
 func TestGetShuffledApprovers(t *testing.T) {
 	rootApprovers := sets.NewString("Alice", "Bob")
 	aApprovers := sets.NewString("Art", "Anne")
@@ -614,13 +1456,13 @@ func TestGetShuffledApprovers(t *testing.T) {
 			testName:      "Combo and D, Seed 0",
 			filenames:     []string{"a/combo/test.go", "a/d/test.go"},
 			seed:          0,
-			expectedOrder: []string{"Erin", "Dan", "Dan", "Carol", "David", "Debbie", "Chris", "Debbie", "Eve", "David"},
+			expectedOrder: []string{"Debbie", "Dan", "David", "Carol", "Erin", "Eve", "Chris"},
 		},
 		{
 			testName:      "Combo and D, Seed 2",
 			filenames:     []string{"a/combo/test.go", "a/d/test.go"},
 			seed:          2,
-			expectedOrder: []string{"Dan", "Carol", "Debbie", "Dan", "Erin", "Chris", "Eve", "David", "Debbie", "David"},
+			expectedOrder: []string{"David", "Carol", "Eve", "Dan", "Debbie", "Chris", "Erin"},
 		},
 	}
 
@@ -633,6 +1475,89 @@ func TestGetShuffledApprovers(t *testing.T) {
 	}
 }
 
+// listingRepo is a FakeRepo that can list its OWNERS directories.
+type listingRepo struct {
+	FakeRepo
+	dirs []string
+}
+
+func (r listingRepo) ListOwnersDirs(root string) []string {
+	listed := []string{}
+	for _, dir := range r.dirs {
+		if root == "" || dir == root || isSubdir(root, dir) {
+			listed = append(listed, dir)
+		}
+	}
+	return listed
+}
+
+func TestListOwnersDirs(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":      sets.NewString("Alice"),
+		"a":     sets.NewString("Art"),
+		"a/b":   sets.NewString("Ben"),
+		"a/b/c": sets.NewString(),
+		"d":     sets.NewString("Dan"),
+	}
+	repo := listingRepo{FakeRepo: createFakeRepo(FakeRepoMap), dirs: []string{"", "a", "a/b", "a/b/c", "d"}}
+
+	tests := []struct {
+		testName string
+		repo     RepoInterface
+		root     string
+		expected []string
+	}{
+		{
+			testName: "Whole Repo",
+			repo:     repo,
+			root:     "",
+			expected: []string{"", "a", "a/b", "a/b/c", "d"},
+		},
+		{
+			testName: "Nested Subtree",
+			repo:     repo,
+			root:     "./a/",
+			expected: []string{"a", "a/b", "a/b/c"},
+		},
+		{
+			testName: "Through An Alias",
+			repo:     NewRepoAlias(repo, *features.NewAliases(map[string][]string{})),
+			root:     "a/b",
+			expected: []string{"a/b", "a/b/c"},
+		},
+	}
+	for _, test := range tests {
+		found, ok := NewOwners([]string{"a/test.go"}, test.repo, TEST_SEED).ListOwnersDirs(test.root)
+		if !ok {
+			t.Errorf("Failed for test %v.  Expected the repo to list its OWNERS directories", test.testName)
+			continue
+		}
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("Failed for test %v.  Expected OWNERS directories: %v. Found %v", test.testName, test.expected, found)
+		}
+	}
+
+	// e.g. audit the directories lacking approvers
+	dirs, _ := NewOwners(nil, repo, TEST_SEED).ListOwnersDirs("a")
+	unowned := []string{}
+	for _, dir := range dirs {
+		if repo.LeafApprovers(dir).Len() == 0 {
+			unowned = append(unowned, dir)
+		}
+	}
+	if expected := []string{"a/b/c"}; !reflect.DeepEqual(unowned, expected) {
+		t.Errorf("Expected directories without approvers: %v. Found %v", expected, unowned)
+	}
+
+	if _, ok := NewOwners(nil, createFakeRepo(FakeRepoMap), TEST_SEED).ListOwnersDirs(""); ok {
+		t.Errorf("Expected a repo without listing not to list its OWNERS directories")
+	}
+	alias := NewRepoAlias(createFakeRepo(FakeRepoMap), *features.NewAliases(map[string][]string{}))
+	if _, ok := NewOwners(nil, alias, TEST_SEED).ListOwnersDirs(""); ok {
+		t.Errorf("Expected an alias of a repo without listing not to list its OWNERS directories")
+	}
+}
+
 func TestRemoveSubdirs(t *testing.T) {
 	tests := []struct {
 		testName    string
@@ -652,20 +1577,124 @@ func TestRemoveSubdirs(t *testing.T) {
 		{
 			testName:    "Two Separate Branches",
 			directories: []string{"a/", "c/"},
-			expected:    sets.NewString("a/", "c/"),
+			expected:    sets.NewString("a", "c"),
 		},
 		{
 			testName:    "Lots of Branches and Leaves",
 			directories: []string{"a", "a/combo", "a/d", "b", "c"},
 			expected:    sets.NewString("a", "b", "c"),
 		},
+		{
+			testName:    "Siblings Sharing a Name Prefix",
+			directories: []string{"/app", "/apps"},
+			expected:    sets.NewString("/app", "/apps"),
+		},
+		{
+			testName:    "Nested Siblings Sharing a Name Prefix",
+			directories: []string{"/a/b", "/a/bc"},
+			expected:    sets.NewString("/a/b", "/a/bc"),
+		},
+		{
+			testName:    "Prefixed Sibling and Real Subdirectory",
+			directories: []string{"app", "app/sub", "apps"},
+			expected:    sets.NewString("app", "apps"),
+		},
+		{
+			testName:    "Deep Subdirectory Before its Parent",
+			directories: []string{"/a/b/c", "/a"},
+			expected:    sets.NewString("/a"),
+		},
+		{
+			testName:    "Deep Subdirectory After its Parent",
+			directories: []string{"/a", "/a/b/c"},
+			expected:    sets.NewString("/a"),
+		},
+		{
+			testName:    "Parent Last",
+			directories: []string{"/a/b", "/c", "/a/d/e", "/a"},
+			expected:    sets.NewString("/a", "/c"),
+		},
+		{
+			testName:    "Equivalent Paths",
+			directories: []string{"a", "./a", "a/", "b\\c", "b/./c"},
+			expected:    sets.NewString("a", "b/c"),
+		},
+		{
+			testName:    "Dot Dot Segments",
+			directories: []string{"a/b/../c", "a/c/d", "."},
+			expected:    sets.NewString(""),
+		},
+		{
+			testName:    "Filter Keys Are Kept",
+			directories: []string{"./b", features.FilteredOwnersKey("./a", ".*\\.yaml$")},
+			expected:    sets.NewString("b", features.FilteredOwnersKey("a", ".*\\.yaml$")),
+		},
 	}
 
 	for _, test := range tests {
-		calculated := removeSubdirs(test.directories)
+		calculated := removeSubdirs(test.directories, nil)
 		if !reflect.DeepEqual(test.expected, calculated) {
 			t.Errorf("Failed to remove subdirectories for test %v.  Expected files: %v. Found %v", test.testName, test.expected, calculated)
 
 		}
+		// The result mustn't depend on the order of the directories.
+		reversed := []string{}
+		for i := len(test.directories) - 1; i >= 0; i-- {
+			reversed = append(reversed, test.directories[i])
+		}
+		if calculated := removeSubdirs(reversed, nil); !reflect.DeepEqual(test.expected, calculated) {
+			t.Errorf("Failed to remove subdirectories for test %v in reverse order.  Expected files: %v. Found %v", test.testName, test.expected, calculated)
+		}
+	}
+}
+
+// TestNormalizedOwnersSet feeds randomly mangled paths and checks that they
+// resolve to the same OWNERS files as the clean paths.
+func TestNormalizedOwnersSet(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":      sets.NewString("Alice"),
+		"a":     sets.NewString("Art"),
+		"a/b":   sets.NewString("Ben"),
+		"c/d/e": sets.NewString("Eve"),
+	}
+	repo := createFakeRepoNoParentOwners(FakeRepoMap, sets.NewString("a/b", "c/d/e"))
+	clean := []string{"test.go", "a/test.go", "a/b/test.go", "a/b/f/test.go", "c/d/e/test.go", "c/test.go"}
+	mangle := []func(string) string{
+		func(p string) string { return "./" + p },
+		func(p string) string { return strings.Replace(p, "/", "//", -1) },
+		func(p string) string { return strings.Replace(p, "/", "\\", -1) },
+		func(p string) string { return "x/../" + p },
+		func(p string) string { return strings.Replace(p, "/", "/./", -1) },
+	}
+
+	r := rand.New(rand.NewSource(TEST_SEED))
+	for i := 0; i < 200; i++ {
+		fn := clean[r.Intn(len(clean))]
+		mangled := fn
+		for j := r.Intn(4); j >= 0; j-- {
+			mangled = mangle[r.Intn(len(mangle))](mangled)
+		}
+		expected := NewOwners([]string{fn}, repo, TEST_SEED).GetOwnersSet()
+		if found := NewOwners([]string{mangled}, repo, TEST_SEED).GetOwnersSet(); !expected.Equal(found) {
+			t.Errorf("Expected %q to resolve like %q to %v. Found %v", mangled, fn, expected, found)
+		}
+		if found := NewOwners([]string{fn, mangled}, repo, TEST_SEED).GetOwnersSet(); !expected.Equal(found) {
+			t.Errorf("Expected %q and %q to resolve to %v. Found %v", fn, mangled, expected, found)
+		}
+	}
+}
+
+func BenchmarkRemoveSubdirs(b *testing.B) {
+	directories := []string{}
+	for i := 0; i < 100; i++ {
+		directories = append(directories, fmt.Sprintf("dir%d", i))
+		for j := 0; j < 9; j++ {
+			directories = append(directories, fmt.Sprintf("dir%d/sub%d", i, j))
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		removeSubdirs(directories, nil)
 	}
 }
@@ -0,0 +1,117 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+// approversState is the serialized form of the approvals, assignees and
+// holds of an Approvers.
+type approversState struct {
+	Approvals []approvalState `json:"approvals"`
+	Assignees []string        `json:"assignees"`
+	Holds     []string        `json:"holds,omitempty"`
+	// CaseSensitive is Approvers.CaseSensitive, which decides whether
+	// approvals differing by case are distinct.
+	CaseSensitive bool `json:"case_sensitive,omitempty"`
+}
+
+type approvalState struct {
+	Login          string     `json:"login"`
+	How            string     `json:"how"`
+	Reference      string     `json:"reference"`
+	SHA            string     `json:"sha,omitempty"`
+	Time           *time.Time `json:"time,omitempty"`
+	ActingLogin    string     `json:"acting_login,omitempty"`
+	CancelledPaths []string   `json:"cancelled_paths,omitempty"`
+	Scope          []string   `json:"scope,omitempty"`
+}
+
+// MarshalState serializes the approvals, assignees and holds of ap, so
+// that they can be restored with LoadState. The owners and options are not
+// saved, except for CaseSensitive.
+func (ap Approvers) MarshalState() ([]byte, error) {
+	state := approversState{
+		Approvals:     []approvalState{},
+		Assignees:     ap.assignees.List(),
+		Holds:         ap.holds.List(),
+		CaseSensitive: ap.CaseSensitive,
+	}
+	for _, approval := range ap.ListApprovals() {
+		saved := approvalState{
+			Login:          approval.Login,
+			How:            string(approval.How),
+			Reference:      approval.Reference,
+			SHA:            approval.SHA,
+			ActingLogin:    approval.ActingLogin,
+			CancelledPaths: approval.cancelledPaths.List(),
+		}
+		if approval.scope != nil {
+			saved.Scope = approval.scope.List()
+		}
+		if !approval.Time.IsZero() {
+			at := approval.Time
+			saved.Time = &at
+		}
+		state.Approvals = append(state.Approvals, saved)
+	}
+	return json.Marshal(state)
+}
+
+// LoadState creates an Approvers for owners with the approvals, assignees
+// and holds serialized by MarshalState.
+func LoadState(owners Owners, data []byte) (Approvers, error) {
+	state := approversState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return Approvers{}, fmt.Errorf("Failed to decode approvers state: %v", err)
+	}
+
+	ap := NewApprovers(owners)
+	ap.CaseSensitive = state.CaseSensitive
+	for _, approval := range state.Approvals {
+		if approval.Login == "" {
+			return Approvers{}, fmt.Errorf("Approval without login in approvers state")
+		}
+		restored := Approval{
+			Login:       approval.Login,
+			How:         How(approval.How),
+			Reference:   approval.Reference,
+			SHA:         approval.SHA,
+			ActingLogin: approval.ActingLogin,
+		}
+		if approval.Time != nil {
+			restored.Time = *approval.Time
+		}
+		if len(approval.CancelledPaths) != 0 {
+			restored.cancelledPaths = sets.NewString(approval.CancelledPaths...)
+		}
+		if len(approval.Scope) != 0 {
+			restored.scope = sets.NewString(approval.Scope...)
+		}
+		ap.approvers[ap.approvalKey(approval.Login)] = restored
+	}
+	ap.AddAssignees(state.Assignees...)
+	for _, login := range state.Holds {
+		ap.AddHold(login)
+	}
+	return ap, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util/sets"
+)
+
+func TestMarshalStateRoundTrip(t *testing.T) {
+	FakeRepoMap := map[string]sets.String{
+		"":  sets.NewString("Alice"),
+		"a": sets.NewString("Art"),
+		"b": sets.NewString("Bill"),
+	}
+	owners := Owners{filenames: []string{"a/test.go", "b/test.go"}, repo: createFakeRepo(FakeRepoMap), seed: TEST_SEED}
+	ap := NewApprovers(owners)
+	ap.AddApproverAt("Alice", "REFERENCE", "sha1", time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC))
+	ap.AddLGTMer("Art", "REFERENCE2", "")
+	ap.RemoveApproverForPath("Alice", "b")
+	ap.AddDelegatedApprover("Dave", "Bill", "REFERENCE3")
+	ap.AddAssignees("Bill")
+	ap.AddHold("Carl")
+
+	data, err := ap.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState() failed: %v", err)
+	}
+	loaded, err := LoadState(owners, data)
+	if err != nil {
+		t.Fatalf("LoadState() failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(ap.ListApprovals(), loaded.ListApprovals()) {
+		t.Errorf("Expected approvals %v. Found %v", ap.ListApprovals(), loaded.ListApprovals())
+	}
+	if ap.IsApproved() != loaded.IsApproved() {
+		t.Errorf("Expected approved %v. Found %v", ap.IsApproved(), loaded.IsApproved())
+	}
+	if !ap.UnapprovedFiles().Equal(loaded.UnapprovedFiles()) {
+		t.Errorf("Expected unapproved files %v. Found %v", ap.UnapprovedFiles(), loaded.UnapprovedFiles())
+	}
+	if !loaded.IsBlocked() || !ap.holds.Equal(loaded.holds) {
+		t.Errorf("Expected holds %v. Found %v", ap.holds, loaded.holds)
+	}
+	if !ap.assignees.Equal(loaded.assignees) {
+		t.Errorf("Expected assignees %v. Found %v", ap.assignees, loaded.assignees)
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	owners := Owners{filenames: []string{"a/test.go"}, repo: createFakeRepo(map[string]sets.String{"a": sets.NewString("Art")}), seed: TEST_SEED}
+	for _, data := range []string{"not json", `{"approvals":[{"how":"Approved"}]}`} {
+		if _, err := LoadState(owners, []byte(data)); err == nil {
+			t.Errorf("Expected an error loading %q", data)
+		}
+	}
+}